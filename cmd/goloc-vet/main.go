@@ -0,0 +1,20 @@
+// Command goloc-vet reports strings passed to the configured functions which have not been extracted by goloc yet.
+//
+// It can be run directly, or by go vet:
+//
+//	go vet -vettool=$(which goloc-vet) -fmtfuncs=Send -funcs=Sendf ./...
+//
+// Run it with -fix to mark the strings with goloc.Add, Addf and Addp, then run goloc extract to store and convert them.
+package main
+
+import (
+	"github.com/rs/zerolog"
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/PaulSonOfLars/goloc/pkg/loc"
+)
+
+func main() {
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	singlechecker.Main(loc.Analyzer)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"go/token"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
@@ -12,11 +15,12 @@ import (
 	"github.com/PaulSonOfLars/goloc/pkg/loc"
 )
 
-func ingestFlagSlices(funcsSlice, fmtfuncsSlice *[]string, l *loc.Locer) {
+func ingestFlagSlices(funcsSlice, fmtfuncsSlice, pluralfuncsSlice *[]string, l *loc.Locer) {
 	l.Funcs = make(map[string]struct{})
 	l.Fmtfuncs = make(map[string]struct{})
+	l.Pluralfuncs = make(map[string]struct{})
 	for src, dest := range map[*[]string]map[string]struct{}{
-		funcsSlice: l.Funcs, fmtfuncsSlice: l.Fmtfuncs} {
+		funcsSlice: l.Funcs, fmtfuncsSlice: l.Fmtfuncs, pluralfuncsSlice: l.Pluralfuncs} {
 		if *src == nil {
 			continue
 		}
@@ -46,20 +50,57 @@ func ingestFlagLang(lang string, l *loc.Locer) {
 	l.DefaultLang = lang
 }
 
+// ingestConfig applies the configuration file at path, or the one found from the current directory if path is empty,
+// to the settings not given as flags. It returns nil if there is no configuration file.
+func ingestConfig(cmd *cobra.Command, path string, l *loc.Locer) (*loc.Config, error) {
+	if path == "" {
+		var err error
+		if path, err = loc.FindConfig("."); err != nil || path == "" {
+			return nil, err
+		}
+	}
+	cfg, err := loc.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	loc.Logger.Debug().Msgf("using config %s", cfg.Path)
+	cfg.Apply(l, cmd.Flags().Changed)
+	return cfg, nil
+}
+
+// writeGenerated writes the output of gen to path if apply is set, or else to stdout. The file is only written once
+// gen succeeds, so a failure leaves any previous version in place; missing directories are created.
+func writeGenerated(apply bool, path string, gen func(w io.Writer) error) error {
+	if !apply {
+		return gen(os.Stdout)
+	}
+	var buf bytes.Buffer
+	if err := gen(&buf); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
 func main() {
 	l := &loc.Locer{
-		DefaultLang: "en-GB",
+		DefaultLang: loc.DefaultLanguage,
 		Checked:     make(map[string]struct{}),
 		Fset:        token.NewFileSet(),
 	}
 
 	var (
-		lang          string
-		debug         = false
-		trace         = false
-		funcsSlice    = make([]string, 0)
-		fmtfuncsSlice = make([]string, 0)
-		log           = loc.Logger
+		lang             string
+		configPath       string
+		cfg              *loc.Config
+		debug            = false
+		trace            = false
+		funcsSlice       = make([]string, 0)
+		fmtfuncsSlice    = make([]string, 0)
+		pluralfuncsSlice = make([]string, 0)
+		log              = loc.Logger
 	)
 
 	rootCmd := cobra.Command{
@@ -69,19 +110,48 @@ func main() {
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			ingestFlagLog(debug, trace)
 			ingestFlagLang(lang, l)
-			ingestFlagSlices(&funcsSlice, &fmtfuncsSlice, l)
+			ingestFlagSlices(&funcsSlice, &fmtfuncsSlice, &pluralfuncsSlice, l)
+			var err error
+			if cfg, err = ingestConfig(cmd, configPath, l); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+			if _, err := loc.FileFormatByName(l.FileFormat); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+			if err := loc.ValidateKeyStrategy(l.KeyStrategy); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+			if err := loc.ValidateLangExprs(l.LangExprs); err != nil {
+				log.Fatal().Err(err).Send()
+			}
 		},
 	}
 
-	rootCmd.PersistentFlags().StringSliceVar(&funcsSlice, "funcs", nil, "all funcs to extraxt")
-	rootCmd.PersistentFlags().StringSliceVar(&fmtfuncsSlice, "fmtfuncs", nil, "all format funcs to extract")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "configuration file (default the goloc.yaml or goloc.toml found from the current directory up to the module root)")
+	rootCmd.PersistentFlags().StringSliceVar(&funcsSlice, "funcs", nil, "all funcs to extraxt, by name or fully qualified, eg (*github.com/foo/tgbot.Bot).Sendf")
+	rootCmd.PersistentFlags().StringSliceVar(&fmtfuncsSlice, "fmtfuncs", nil, "all format funcs to extract, by name or fully qualified, eg fmt.Println")
+	rootCmd.PersistentFlags().StringSliceVar(&pluralfuncsSlice, "pluralfuncs", nil, "all plural funcs to extract, called as f(one, other, n, args...), by name or fully qualified")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "v", false, "add extra verbosity")
 	rootCmd.PersistentFlags().BoolVarP(&trace, "trace", "V", false, "add trace verbosity")
 	rootCmd.PersistentFlags().BoolVarP(&l.Apply, "apply", "a", false, "save to file")
-	rootCmd.PersistentFlags().StringVarP(&lang, "lang", "l", language.BritishEnglish.String(), "")
+	rootCmd.PersistentFlags().StringVarP(&lang, "lang", "l", loc.DefaultLanguage, "default language")
+	rootCmd.PersistentFlags().StringVar(&l.TransDir, "trans-dir", loc.DefaultTranslationDir, "root directory of the translation files")
+	rootCmd.PersistentFlags().StringVar(&l.FileFormat, "file-format", "xml", "format of the translation files (xml, json, yaml)")
+	rootCmd.PersistentFlags().BoolVar(&l.ICU, "icu", false, "treat values as ICU MessageFormat messages")
+	rootCmd.PersistentFlags().BoolVar(&l.Generated, "generated", false, "also inspect and extract generated files")
+	rootCmd.PersistentFlags().BoolVar(&l.Tests, "tests", false, "also inspect and extract _test.go files")
+	rootCmd.PersistentFlags().StringSliceVar(&l.Include, "include", nil, "only inspect and extract files matching these globs, eg bot/**")
+	rootCmd.PersistentFlags().StringSliceVar(&l.Exclude, "exclude", nil, "skip files and directories matching these globs, eg legacy,cmd/tools/**")
+	rootCmd.PersistentFlags().StringSliceVar(&l.Tags, "tags", nil, "build tags source files are selected with")
+	rootCmd.PersistentFlags().StringVar(&l.GOOS, "goos", "", "GOOS source files are selected for (default the current one)")
+	rootCmd.PersistentFlags().StringVar(&l.GOARCH, "goarch", "", "GOARCH source files are selected for (default the current one)")
+	rootCmd.PersistentFlags().StringArrayVar(&l.LangExprs, "lang-expr", nil, "expression extract sets lang to, in functions where its identifiers are in scope, eg 's.lang(msg.From)'; may be repeated (default getLang(u))")
+	rootCmd.PersistentFlags().BoolVar(&l.UseContext, "use-context", false, "convert calls in functions with a context.Context parameter to goloc.T, Tf and Tpf with it, instead of setting lang")
+	rootCmd.PersistentFlags().StringVar(&l.KeyStrategy, "key-strategy", loc.KeyCounter, "how extracted strings are named (counter, hash)")
+	rootCmd.PersistentFlags().BoolVar(&l.FormatNumbers, "format-numbers", false, "extract %d as locale-formatted {n,number} placeholders")
 
 	rootCmd.AddCommand(&cobra.Command{
-		Use:   "inspect",
+		Use:   "inspect [files, dirs or packages]",
 		Short: "Run an analyse all appropriate strings in specified files",
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := l.Handle(args, l.Inspect); err != nil {
@@ -91,7 +161,7 @@ func main() {
 	})
 
 	rootCmd.AddCommand(&cobra.Command{
-		Use:   "extract",
+		Use:   "extract [files, dirs or packages]",
 		Short: "extract all strings",
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := l.Handle(args, l.Fix); err != nil {
@@ -105,6 +175,20 @@ func main() {
 		Use:   "create",
 		Short: "create new language from default",
 		Run: func(cmd *cobra.Command, args []string) {
+			if createLang == "" && cfg != nil && len(cfg.Langs) > 0 {
+				// create the configured languages which don't exist yet.
+				for _, cl := range cfg.Langs {
+					langTag := language.Make(cl)
+					if langTag == language.Und {
+						log.Fatal().Msgf("invalid language configured: '%v' does not match any known language codes", cl)
+					}
+					if _, err := os.Stat(filepath.Join(l.TransDir, langTag.String())); err == nil || langTag.String() == l.DefaultLang {
+						continue
+					}
+					l.Create(args, langTag)
+				}
+				return
+			}
 			if createLang == "" {
 				log.Error().Msg("No language to create specified")
 				return
@@ -117,10 +201,12 @@ func main() {
 			l.Create(args, langTag)
 		},
 	}
-	createCmd.Flags().StringVarP(&createLang, "create", "c", "", "select which language to create")
+	createCmd.Flags().StringVarP(&createLang, "create", "c", "", "select which language to create (default the configured langs not created yet)")
 	rootCmd.AddCommand(createCmd)
 
 	checkLang := "all"
+	checkOutput := "text"
+	checkFailOn := "error"
 	checkCmd := &cobra.Command{
 		Use:   "check",
 		Short: "Check integrity of language files",
@@ -137,11 +223,117 @@ func main() {
 			if err != nil {
 				log.Fatal().Err(err).Send()
 			}
+			if err := loc.WriteDiagnostics(os.Stdout, checkOutput, l.Diagnostics); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+			if cfg != nil && cfg.Check.FailOn != "" && !cmd.Flags().Changed("fail-on") {
+				checkFailOn = cfg.Check.FailOn
+			}
+			if checkFailOn == "none" {
+				return
+			}
+			threshold, err := loc.ParseSeverity(checkFailOn)
+			if err != nil {
+				log.Fatal().Err(err).Send()
+			}
+			if worst, ok := loc.MaxSeverity(l.Diagnostics); ok && worst >= threshold {
+				os.Exit(1)
+			}
 		},
 	}
 	checkCmd.Flags().StringVarP(&checkLang, "check", "c", "all", "select which language to check")
+	checkCmd.Flags().StringVarP(&checkOutput, "output", "o", "text", "output format (text, json, sarif)")
+	checkCmd.Flags().StringVar(&checkFailOn, "fail-on", "error", "exit with status 1 if any finding is at least this severe (info, warning, error, none)")
 	rootCmd.AddCommand(checkCmd)
 
+	var statsFormat string
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "show how complete each language is",
+		Run: func(cmd *cobra.Command, args []string) {
+			stats, err := l.Stats()
+			if err != nil {
+				log.Fatal().Err(err).Send()
+			}
+			if err := loc.WriteStats(os.Stdout, statsFormat, stats); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+		},
+	}
+	statsCmd.Flags().StringVarP(&statsFormat, "format", "f", "table", "output format (table, json, csv)")
+	rootCmd.AddCommand(statsCmd)
+
+	var exportFormat, exportOut string
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "export all languages for translators",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := l.Export(exportFormat, exportOut); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+		},
+	}
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "po", "export format (po, xliff, xliff2)")
+	exportCmd.Flags().StringVarP(&exportOut, "out", "o", "export", "directory to write the exported files to")
+	rootCmd.AddCommand(exportCmd)
+
+	var importFormat string
+	importCmd := &cobra.Command{
+		Use:   "import [files]",
+		Short: "merge translated files back into the language files",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := l.Import(importFormat, args); err != nil {
+				log.Fatal().Err(err).Send()
+			}
+		},
+	}
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", "", "import format (po, xliff); guessed from the file extension if unset")
+	rootCmd.AddCommand(importCmd)
+
+	var generateOut, generatePkg string
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "compile all language files into a go file, so binaries need no translation files",
+		Run: func(cmd *cobra.Command, args []string) {
+			if generateOut == "" {
+				generateOut = filepath.Join(l.TransDir, "goloc_gen.go")
+			}
+			if generatePkg == "" {
+				generatePkg = filepath.Base(filepath.Dir(generateOut))
+			}
+			err := writeGenerated(l.Apply, generateOut, func(w io.Writer) error {
+				return l.Generate(w, generatePkg)
+			})
+			if err != nil {
+				log.Fatal().Err(err).Send()
+			}
+		},
+	}
+	generateCmd.Flags().StringVarP(&generateOut, "out", "o", "", "file to generate (default <trans-dir>/goloc_gen.go)")
+	generateCmd.Flags().StringVarP(&generatePkg, "package", "p", "", "package name of the generated file (default the name of its directory)")
+	rootCmd.AddCommand(generateCmd)
+
+	var keysOut, keysPkg string
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "generate typed constants and accessor functions for every key of the default language",
+		Run: func(cmd *cobra.Command, args []string) {
+			if keysPkg == "" {
+				keysPkg = filepath.Base(filepath.Dir(keysOut))
+			}
+			err := writeGenerated(l.Apply, keysOut, func(w io.Writer) error {
+				return l.GenerateKeys(w, keysPkg)
+			})
+			if err != nil {
+				log.Fatal().Err(err).Send()
+			}
+		},
+	}
+	keysCmd.Flags().StringVarP(&keysOut, "out", "o", filepath.Join("msg", "keys_gen.go"), "file to generate")
+	keysCmd.Flags().StringVarP(&keysPkg, "package", "p", "", "package name of the generated file (default the name of its directory)")
+	rootCmd.AddCommand(keysCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
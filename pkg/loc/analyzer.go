@@ -0,0 +1,292 @@
+package loc
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports string literals passed to the configured funcs, fmtfuncs and pluralfuncs, which goloc extract
+// would convert into goloc calls. Its suggested fixes mark the strings with goloc.Add, Addf or Addp, which goloc
+// extract then stores and converts; the analyzer itself writes nothing.
+var Analyzer = NewAnalyzer()
+
+const analyzerDoc = `report unlocalised strings passed to the configured functions
+
+Calls to -funcs, -fmtfuncs and -pluralfuncs with string literals are reported, with a suggested fix wrapping the
+strings in goloc.Add, goloc.Addf or goloc.Addp. These keep working as before until "goloc extract" is run, which
+stores the marked strings in the translation files and converts them to goloc.Trnl, Trnlf or Trnpf calls.
+
+Funcs are given by name, matching any call of that name, or fully qualified, such as fmt.Printf or
+(*github.com/foo/tgbot.Bot).Send, matching only calls to that function or method.
+
+Strings in functions where goloc extract cannot set lang are reported without a fix; see -lang-expr. With
+-use-context, functions with a context.Context parameter pass it to goloc.T, Tf and Tpf instead.
+
+Settings not given as flags are read from the goloc.yaml or goloc.toml of the module, as goloc does.`
+
+// NewAnalyzer returns a goloc Analyzer with its own flags, so separate instances don't share their settings.
+func NewAnalyzer() *analysis.Analyzer {
+	c := &Locer{
+		Funcs:       make(map[string]struct{}),
+		Fmtfuncs:    make(map[string]struct{}),
+		Pluralfuncs: make(map[string]struct{}),
+	}
+	a := &analysis.Analyzer{
+		Name: "goloc",
+		Doc:  analyzerDoc,
+		URL:  "https://" + runtimeImport,
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return runAnalyzer(pass, c)
+		},
+	}
+	a.Flags.Var(funcSet(c.Funcs), "funcs", "comma-separated funcs to extract")
+	a.Flags.Var(funcSet(c.Fmtfuncs), "fmtfuncs", "comma-separated format funcs to extract")
+	a.Flags.Var(funcSet(c.Pluralfuncs), "pluralfuncs", "comma-separated plural funcs to extract, called as f(one, other, n, args...)")
+	a.Flags.Var((*exprList)(&c.LangExprs), "lang-expr", "expression to set lang to, in functions where its identifiers are in scope; may be repeated")
+	a.Flags.BoolVar(&c.UseContext, "use-context", false, "convert calls in functions with a context.Context parameter to goloc.T, Tf and Tpf with it")
+	return a
+}
+
+// funcSet is a flag.Value adding comma-separated names to a set.
+type funcSet map[string]struct{}
+
+func (s funcSet) String() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (s funcSet) Set(v string) error {
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s[name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// exprList is a flag.Value collecting each use of the flag, as expressions may contain commas.
+type exprList []string
+
+func (e *exprList) String() string {
+	return strings.Join(*e, " ")
+}
+
+func (e *exprList) Set(v string) error {
+	if err := ValidateLangExprs([]string{v}); err != nil {
+		return err
+	}
+	*e = append(*e, v)
+	return nil
+}
+
+// runAnalyzer reports the strings of pass, with the settings of c. The Locer it uses is local to the pass, so passes
+// may run concurrently.
+func runAnalyzer(pass *analysis.Pass, c *Locer) (interface{}, error) {
+	if len(pass.Files) == 0 {
+		return nil, nil
+	}
+	l := &Locer{
+		Funcs:       c.Funcs,
+		Fmtfuncs:    c.Fmtfuncs,
+		Pluralfuncs: c.Pluralfuncs,
+		Fset:        pass.Fset,
+		LangExprs:   c.LangExprs,
+		UseContext:  c.UseContext,
+	}
+	cfgPath, err := FindConfig(filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name()))
+	if err != nil {
+		return nil, err
+	}
+	if cfgPath != "" {
+		cfg, err := LoadConfig(cfgPath)
+		if err != nil {
+			return nil, err
+		}
+		set := make(map[string]bool)
+		pass.Analyzer.Flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		cfg.Apply(l, func(name string) bool { return set[name] })
+	}
+	l.info = pass.TypesInfo
+	for _, file := range pass.Files {
+		filename := pass.Fset.File(file.Pos()).Name()
+		if ast.IsGenerated(file) || !strings.HasSuffix(filename, ".go") {
+			continue
+		}
+		l.analyzeFile(pass, file)
+	}
+	return nil, nil
+}
+
+func (l *Locer) analyzeFile(pass *analysis.Pass, file *ast.File) {
+	// every fix adds the same goloc import, so the driver applies it once when merging the fixes of the file.
+	imports := importEdits(file, []string{runtimeImport})
+
+	for _, decl := range file.Decls {
+		fn, _ := decl.(*ast.FuncDecl)
+		langOK := true
+		if containsCall(decl, l.extractable) {
+			switch {
+			case fn != nil && l.UseContext && contextParam(file, fn) != "":
+				// extract uses the context instead of lang
+			case fn != nil:
+				_, langOK = l.langStmt(file, fn)
+			default:
+				langOK = false
+			}
+			if !langOK && fn != nil {
+				pass.Reportf(fn.Name.Pos(), "no lang expression applies in %s, so its strings cannot be extracted", fn.Name.Name)
+			} else if !langOK {
+				pass.Reportf(decl.Pos(), "strings outside of functions cannot be extracted, as there is no lang")
+			}
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if !langOK && l.extractable(call) {
+				pass.Reportf(call.Pos(), "unlocalised string passed to %s", types.ExprString(call.Fun))
+				return false
+			}
+			newCall, ok, err := l.markCall(call)
+			if err != nil {
+				pass.Reportf(call.Pos(), "cannot extract call to %s: %v", types.ExprString(call.Fun), err)
+				return true
+			} else if !ok {
+				return true
+			}
+
+			var buf bytes.Buffer
+			if err := format.Node(&buf, pass.Fset, newCall); err != nil {
+				pass.Reportf(call.Pos(), "cannot extract call to %s: %v", types.ExprString(call.Fun), err)
+				return true
+			}
+			mark := newCall.Args[0].(*ast.CallExpr)
+			pass.Report(analysis.Diagnostic{
+				Pos:     call.Pos(),
+				End:     call.End(),
+				Message: fmt.Sprintf("unlocalised string passed to %s", types.ExprString(call.Fun)),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: fmt.Sprintf("Mark %s for goloc extract with %s", mark.Args[0].(*ast.BasicLit).Value,
+						types.ExprString(mark.Fun)),
+					TextEdits: append([]analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: buf.Bytes()}}, imports...),
+				}},
+			})
+			return false
+		})
+	}
+}
+
+// markCall returns call with its strings wrapped in goloc.Add, Addf or Addp, for goloc extract to convert. ok is
+// false if call is not to one of the funcs, fmtfuncs or pluralfuncs, or doesn't pass string literals; err is set if
+// extract could not convert it.
+func (l *Locer) markCall(call *ast.CallExpr) (out *ast.CallExpr, ok bool, err error) {
+	id := calleeIdent(call)
+	if id == nil || len(call.Args) == 0 {
+		return nil, false, nil
+	}
+	funcEntry, funcOK := l.matchFunc(l.Funcs, call)
+	fmtEntry, fmtOK := l.matchFunc(l.Fmtfuncs, call)
+	pluralEntry, pluralOK := l.matchFunc(l.Pluralfuncs, call)
+
+	var mark, newName string
+	var args []ast.Expr
+	switch {
+	case pluralOK && len(call.Args) >= 3:
+		if !isStringLit(call.Args[0]) || !isStringLit(call.Args[1]) {
+			return nil, false, nil
+		}
+		fmtCall := &ast.CallExpr{Args: call.Args[1:], Ellipsis: call.Ellipsis}
+		for _, form := range call.Args[:2] {
+			if err := checkFormat(form.(*ast.BasicLit), fmtCall); err != nil {
+				return nil, false, err
+			}
+		}
+		mark, args, newName = "Addp", call.Args, l.getUnPluralFunc(pluralEntry, id.Name)
+	case funcOK && isStringLit(call.Args[0]):
+		if err := checkFormat(call.Args[0].(*ast.BasicLit), call); err != nil {
+			return nil, false, err
+		}
+		mark, args, newName = "Addf", call.Args, l.getUnFmtFunc(funcEntry, id.Name)
+	case fmtOK && isStringLit(call.Args[0]):
+		mark, args, newName = "Add", call.Args[:1], l.getUnFmtFunc(fmtEntry, id.Name)
+	default:
+		return nil, false, nil
+	}
+
+	return &ast.CallExpr{
+		Fun:    renamedFun(call, id, newName),
+		Lparen: call.Lparen,
+		Args: []ast.Expr{&ast.CallExpr{
+			Fun: &ast.SelectorExpr{
+				X:   &ast.Ident{Name: "goloc"},
+				Sel: &ast.Ident{Name: mark},
+			},
+			Args:     args,
+			Ellipsis: call.Ellipsis,
+		}},
+		Rparen: call.Rparen,
+	}, true, nil
+}
+
+// checkFormat returns the error extract would give converting the format string lit, with the arguments of call.
+func checkFormat(lit *ast.BasicLit, call *ast.CallExpr) error {
+	text, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return err
+	}
+	_, err = newFmtPlaceholders().convert([]rune(text), call)
+	return err
+}
+
+// importEdits returns the edits adding any of paths which file doesn't import yet.
+func importEdits(file *ast.File, paths []string) []analysis.TextEdit {
+	have := make(map[string]struct{})
+	for _, imp := range file.Imports {
+		if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+			have[p] = struct{}{}
+		}
+	}
+	var importDecl *ast.GenDecl
+	for _, decl := range file.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			importDecl = d
+			break
+		}
+	}
+
+	var edits []analysis.TextEdit
+	for _, p := range paths {
+		if _, ok := have[p]; ok {
+			continue
+		}
+		have[p] = struct{}{}
+		var edit analysis.TextEdit
+		switch {
+		case importDecl == nil:
+			edit = analysis.TextEdit{Pos: file.Name.End(), NewText: []byte("\n\nimport " + strconv.Quote(p))}
+		case importDecl.Lparen.IsValid():
+			edit = analysis.TextEdit{Pos: importDecl.Lparen + 1, NewText: []byte("\n\t" + strconv.Quote(p))}
+		default:
+			edit = analysis.TextEdit{Pos: importDecl.End(), NewText: []byte("\nimport " + strconv.Quote(p))}
+		}
+		edit.End = edit.Pos
+		edits = append(edits, edit)
+	}
+	return edits
+}
@@ -0,0 +1,97 @@
+package loc
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func newTestAnalyzer(t *testing.T) *analysis.Analyzer {
+	a := NewAnalyzer()
+	for flag, value := range map[string]string{
+		"funcs":       "Sendf",
+		"fmtfuncs":    "Send",
+		"pluralfuncs": "Sendp",
+	} {
+		if err := a.Flags.Set(flag, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a
+}
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), newTestAnalyzer(t), "extract")
+}
+
+// TestAnalyzerMergedFixes applies the fixes of every diagnostic at once, as go vet -fix does, dropping identical
+// edits, and checks the result against merged.go.golden.
+func TestAnalyzerMergedFixes(t *testing.T) {
+	results := analysistest.Run(t, analysistest.TestData(), newTestAnalyzer(t), "merged")
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	var path string
+	for _, r := range results {
+		for _, d := range r.Diagnostics {
+			for _, fix := range d.SuggestedFixes {
+				for _, e := range fix.TextEdits {
+					file := r.Pass.Fset.File(e.Pos)
+					path = file.Name()
+					end := e.End
+					if !end.IsValid() {
+						end = e.Pos
+					}
+					edits = append(edits, edit{file.Offset(e.Pos), file.Offset(end), string(e.NewText)})
+				}
+			}
+		}
+	}
+	if len(edits) == 0 {
+		t.Fatal("no suggested fixes")
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end < edits[j].end
+	})
+	var out []byte
+	last := 0
+	for i, e := range edits {
+		if i > 0 && e == edits[i-1] {
+			continue
+		}
+		if e.start < last {
+			t.Fatalf("overlapping edits at offset %d", e.start)
+		}
+		out = append(append(out, src[last:e.start]...), e.text...)
+		last = e.end
+	}
+	out = append(out, src[last:]...)
+
+	got, err := format.Source(out)
+	if err != nil {
+		t.Fatalf("merged fixes don't parse: %v\n%s", err, out)
+	}
+	want, err := os.ReadFile(filepath.Join(analysistest.TestData(), "src", "merged", "merged.go.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("merged fixes give:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,290 @@
+package loc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Catalog holds a set of loaded translations, keyed by language and translation name.
+// All methods are safe for concurrent use, so a Catalog can be read from while it is being loaded.
+type Catalog struct {
+	mu          sync.RWMutex
+	defaultLang string
+	data        map[string]map[string]Value // lang:(name:Value)
+	dataCount   map[string]int              // module:counter
+	languages   []string
+	files       map[fileKey]Translation // raw file contents, kept so reloads can drop stale rows
+	static      map[fileKey]Translation // compiled in with AddModule; reloads start from these
+	fsys        fs.FS                   // source of translation files; nil means dir on disk
+	dir         string
+	fallbacks   map[string][]string // lang:explicit fallbacks
+	chains      map[string][]string // lang:cached fallback chain
+	matcher     *langMatcher
+	format      FileFormat // preferred format of translation files
+	icu         bool       // render values as ICU MessageFormat
+	parsed      icuCache
+}
+
+type fileKey struct {
+	lang   string
+	module string
+}
+
+// NewCatalog returns an empty Catalog which falls back to defLang for missing translations.
+func NewCatalog(defLang string) *Catalog {
+	return &Catalog{
+		defaultLang: defLang,
+		data:        make(map[string]map[string]Value),
+		dataCount:   make(map[string]int),
+		files:       make(map[fileKey]Translation),
+		dir:         DefaultTranslationDir,
+		format:      XMLFormat,
+	}
+}
+
+// NewCatalogFS returns an empty Catalog which loads its translation files from fsys, such as an embed.FS.
+// fsys should be rooted at the translation directory, ie contain one directory per language.
+func NewCatalogFS(defLang string, fsys fs.FS) *Catalog {
+	c := NewCatalog(defLang)
+	c.fsys = fsys
+	return c
+}
+
+// SetDir makes future loads read translation files from dir on disk, replacing any fs.FS set with SetFS.
+func (c *Catalog) SetDir(dir string) {
+	c.mu.Lock()
+	c.dir = dir
+	c.fsys = nil
+	c.mu.Unlock()
+}
+
+// SetFS changes where future loads read translation files from. Passing nil reverts to the on-disk directory.
+func (c *Catalog) SetFS(fsys fs.FS) {
+	c.mu.Lock()
+	c.fsys = fsys
+	c.mu.Unlock()
+}
+
+// SetFileFormat sets the format of the translation files to load. Modules which aren't available in that format are
+// still loaded from any other format.
+func (c *Catalog) SetFileFormat(format FileFormat) {
+	c.mu.Lock()
+	c.format = format
+	c.mu.Unlock()
+}
+
+func (c *Catalog) fileFormat() FileFormat {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.format
+}
+
+func (c *Catalog) source() fs.FS {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.fsys == nil {
+		return os.DirFS(c.dir)
+	}
+	return c.fsys
+}
+
+func (c *Catalog) DefaultLang() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.defaultLang
+}
+
+func (c *Catalog) SetDefaultLang(lang string) {
+	c.mu.Lock()
+	c.defaultLang = lang
+	c.chains = nil
+	c.mu.Unlock()
+}
+
+// Trnl returns the translation of trnlVal in the first language of lang's FallbackChain which has one.
+func (c *Catalog) Trnl(lang string, trnlVal string) string {
+	return c.resolve(lang, trnlVal, func(_ string, v Value) string {
+		return v.text(nil)
+	})
+}
+
+// Trnlf is Trnl with the {key} placeholders replaced from dataMap. dataMap also drives any select in the message.
+// Typed placeholders such as {key,number} or {key,date} are formatted for the language of the translation used.
+func (c *Catalog) Trnlf(lang string, trnlVal string, dataMap map[string]string) string {
+	if c.icuEnabled() {
+		return c.resolve(lang, trnlVal, func(l string, v Value) string {
+			return c.renderICU(l, trnlVal, v.text(dataMap), dataMap)
+		})
+	}
+
+	return replacePlaceholders(c.resolve(lang, trnlVal, func(l string, v Value) string {
+		return formatArgs(l, v.text(dataMap), dataMap)
+	}), dataMap)
+}
+
+// resolve walks lang's FallbackChain, returning the first non-empty text pick returns for trnlVal.
+func (c *Catalog) resolve(lang string, trnlVal string, pick func(lang string, v Value) string) string {
+	chain := c.chain(lang)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range chain {
+		v, ok := c.data[l][trnlVal]
+		if !ok {
+			continue
+		}
+		if text := pick(l, v); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+func (c *Catalog) LoadAll(defLang string) {
+	err := walkModules(c.source(), defLang, func(file string) error {
+		c.Load(moduleOf(file))
+		return nil
+	})
+	if err != nil {
+		Logger.Error().Err(err).Msgf("Failed to walk translations directory %s", defLang)
+	}
+}
+
+func (c *Catalog) LoadLangAll(lang string) {
+	fsys := c.source()
+	err := walkModules(fsys, lang, func(file string) error {
+		c.loadLangModule(fsys, lang, moduleOf(file))
+		return nil
+	})
+	if err != nil {
+		Logger.Error().Err(err).Msgf("Failed to walk translations directory %s", lang)
+	}
+}
+
+func (c *Catalog) LoadLangModule(lang string, moduleName string) {
+	c.loadLangModule(c.source(), lang, moduleName)
+}
+
+func (c *Catalog) loadLangModule(fsys fs.FS, lang string, moduleName string) {
+	xmlData, err := readModule(fsys, c.fileFormat(), lang, moduleName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		Logger.Error().Err(err).Send()
+		return
+	}
+	c.add(path.Base(lang), moduleName, xmlData)
+}
+
+// AddModule adds the translations of a module which were compiled into the binary, as done by goloc generate.
+// They are kept across reloads, with files on disk taking precedence.
+func (c *Catalog) AddModule(lang string, moduleName string, t Translation) {
+	c.mu.Lock()
+	if c.static == nil {
+		c.static = make(map[fileKey]Translation)
+	}
+	c.static[fileKey{lang: lang, module: moduleName}] = t
+	c.mu.Unlock()
+	c.add(lang, moduleName, t)
+}
+
+// add merges decoded module data into the catalog; decoding happens before the lock is taken.
+func (c *Catalog) add(lang string, moduleName string, xmlData Translation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[fileKey{lang: lang, module: moduleName}] = xmlData
+	if _, ok := c.data[lang]; !ok {
+		c.data[lang] = make(map[string]Value)
+		c.languages = nil
+	}
+	for _, row := range xmlData.Rows {
+		if row.Name == "" { // ignore empties
+			continue
+		}
+		c.data[lang][row.Name] = row
+	}
+	count := xmlData.Counter
+	if count <= 0 {
+		count = len(xmlData.Rows)
+	}
+	if c.dataCount[moduleName] < count {
+		c.dataCount[moduleName] = count
+	}
+}
+
+func (c *Catalog) Load(moduleToLoad string) {
+	fsys := c.source()
+	langs, err := langDirs(fsys)
+	if err != nil {
+		Logger.Error().Err(err).Msgf("failed to load %s", moduleToLoad)
+		return
+	}
+	for _, lang := range langs {
+		c.loadLangModule(fsys, lang, moduleToLoad)
+	}
+}
+
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	ss := c.languages
+	c.mu.RUnlock()
+	if ss != nil {
+		return slices.Clone(ss)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ss = make([]string, 0, len(c.data))
+	for k := range c.data {
+		ss = append(ss, k)
+	}
+	sort.Strings(ss)
+	c.languages = ss
+	return slices.Clone(ss)
+}
+
+func (c *Catalog) IsLangSupported(s string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.data[s]
+	return ok
+}
+
+// Lookup returns the raw Value stored for the given language and name, without any fallback.
+func (c *Catalog) Lookup(lang string, name string) (Value, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.data[lang][name]
+	return v, ok
+}
+
+// Values returns a copy of every Value loaded for lang.
+func (c *Catalog) Values(lang string) map[string]Value {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]Value, len(c.data[lang]))
+	for k, v := range c.data[lang] {
+		out[k] = v
+	}
+	return out
+}
+
+// Count returns the current id counter for a module.
+func (c *Catalog) Count(moduleName string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.dataCount[moduleName]
+}
+
+// nextID increments the counter of a module and returns the new value.
+func (c *Catalog) nextID(moduleName string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dataCount[moduleName]++
+	return c.dataCount[moduleName]
+}
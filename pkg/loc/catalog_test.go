@@ -0,0 +1,182 @@
+package loc
+
+import (
+	"encoding/xml"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/text/language"
+)
+
+func TestCatalogIndependent(t *testing.T) {
+	a := NewCatalog("en-US")
+	b := NewCatalog("en-US")
+	a.add("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "hello"}}})
+	b.add("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "howdy"}}})
+	a.add("fr-FR", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "bonjour"}}})
+
+	if got := a.Trnl("fr-FR", "mod.go:1"); got != "bonjour" {
+		t.Errorf("a.Trnl(fr-FR) = %q, want %q", got, "bonjour")
+	}
+	if got := b.Trnl("fr-FR", "mod.go:1"); got != "howdy" {
+		t.Errorf("b.Trnl(fr-FR) = %q, want %q", got, "howdy")
+	}
+	if got := a.Trnlf("de-DE", "mod.go:1", nil); got != "hello" {
+		t.Errorf("a.Trnlf(de-DE) = %q, want %q", got, "hello")
+	}
+	if b.IsLangSupported("fr-FR") {
+		t.Error("b should not support fr-FR")
+	}
+}
+
+func TestDefaultLangVar(t *testing.T) {
+	if got := DefaultCatalog().DefaultLang(); got != "en-US" {
+		t.Errorf("runtime default language is %s, want en-US", got)
+	}
+	defer SetDefaultLang(RuntimeDefaultLanguage)
+
+	SetDefaultLang("de-DE")
+	if DefaultLang != "de-DE" || DefaultCatalog().DefaultLang() != "de-DE" {
+		t.Errorf("SetDefaultLang(de-DE) gives DefaultLang %s, catalog %s", DefaultLang, DefaultCatalog().DefaultLang())
+	}
+	DefaultLang = "fr-FR"
+	if got := DefaultCatalog().DefaultLang(); got != "fr-FR" {
+		t.Errorf("assigning DefaultLang = fr-FR gives catalog %s", got)
+	}
+	// setting the catalog directly isn't undone by the unchanged variable.
+	DefaultCatalog().SetDefaultLang("it-IT")
+	if got := DefaultCatalog().DefaultLang(); got != "it-IT" {
+		t.Errorf("catalog default %s, want it-IT", got)
+	}
+}
+
+func TestCatalogConcurrent(t *testing.T) {
+	c := NewCatalog("en-US")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			name := "mod.go:" + strconv.Itoa(i)
+			c.add("en-US", "mod.go", Translation{Rows: []Value{{Id: i, Name: name, Value: name}}})
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_ = c.Trnl("en-US", "mod.go:"+strconv.Itoa(i))
+			_ = c.Languages()
+		}(i)
+	}
+	wg.Wait()
+	if got := c.Trnl("en-US", "mod.go:3"); got != "mod.go:3" {
+		t.Errorf("Trnl() = %q, want %q", got, "mod.go:3")
+	}
+}
+
+func xmlFile(rows ...Value) *fstest.MapFile {
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	if err := xml.NewEncoder(&sb).Encode(Translation{Rows: rows}); err != nil {
+		panic(err)
+	}
+	return &fstest.MapFile{Data: []byte(sb.String())}
+}
+
+func TestCatalogFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"en-US/bot/main.xml": xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "hello"}),
+		"de-DE/bot/main.xml": xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "hallo"}),
+	}
+	c := NewCatalogFS("en-US", fsys)
+	c.LoadAll("en-US")
+
+	if got := c.Trnl("de-DE", "bot/main.go:1"); got != "hallo" {
+		t.Errorf("Trnl(de-DE) = %q, want %q", got, "hallo")
+	}
+	if got, want := c.Languages(), []string{"de-DE", "en-US"}; !slices.Equal(got, want) {
+		t.Errorf("Languages() = %v, want %v", got, want)
+	}
+
+	fsys["de-DE/bot/main.xml"] = xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "servus"})
+	fsys["en-US/bot/main.xml"] = &fstest.MapFile{Data: []byte("<translation><broken")}
+	if err := c.Reload(); err == nil {
+		t.Error("Reload() should report the broken file")
+	}
+	if got := c.Trnl("de-DE", "bot/main.go:1"); got != "servus" {
+		t.Errorf("Trnl(de-DE) after reload = %q, want %q", got, "servus")
+	}
+	if got := c.Trnl("fr-FR", "bot/main.go:1"); got != "hello" {
+		t.Errorf("Trnl(fr-FR) after failed reload = %q, want previous default %q", got, "hello")
+	}
+}
+
+func TestCatalogFallback(t *testing.T) {
+	c := NewCatalog("en-US")
+	c.add("en-US", "mod.go", Translation{Rows: []Value{
+		{Id: 1, Name: "mod.go:1", Value: "colour"},
+		{Id: 2, Name: "mod.go:2", Value: "bus"},
+	}})
+	c.add("de", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "Farbe"}}})
+	c.add("pt-PT", "mod.go", Translation{Rows: []Value{{Id: 2, Name: "mod.go:2", Value: "autocarro"}}})
+	c.add("pt-BR", "mod.go", Translation{Rows: []Value{{Id: 2, Name: "mod.go:2", Value: ""}}})
+
+	if got, want := c.FallbackChain("de-AT"), []string{"de-AT", "de", "en-US", "en"}; !slices.Equal(got, want) {
+		t.Errorf("FallbackChain(de-AT) = %v, want %v", got, want)
+	}
+	if got := c.Trnl("de-AT", "mod.go:1"); got != "Farbe" {
+		t.Errorf("Trnl(de-AT) = %q, want %q", got, "Farbe")
+	}
+	if got := c.Trnl("pt-BR", "mod.go:2"); got != "bus" {
+		t.Errorf("Trnl(pt-BR) without explicit fallback = %q, want %q", got, "bus")
+	}
+
+	c.SetFallbacks("pt-BR", "pt-PT")
+	if got := c.Trnl("pt-BR", "mod.go:2"); got != "autocarro" {
+		t.Errorf("Trnl(pt-BR) with explicit fallback = %q, want %q", got, "autocarro")
+	}
+
+	// languages the catalog doesn't know of, such as from requests, aren't cached.
+	for i := 0; i < 100; i++ {
+		c.Trnl("xx-"+strconv.Itoa(i), "mod.go:1")
+	}
+	c.Trnl("de", "mod.go:1")
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.chains["de"]; !ok || len(c.chains) > 4 {
+		t.Errorf("cached chains %v, want only known languages", c.chains)
+	}
+}
+
+func TestCatalogMatch(t *testing.T) {
+	c := NewCatalog("en-US")
+	for _, lang := range []string{"en-US", "de", "pt-PT", "fr-FR", "de_CH", "zh-hant-tw"} {
+		c.add(lang, "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: lang}}})
+	}
+
+	tests := []struct {
+		prefs    []string
+		want     string
+		wantConf language.Confidence
+	}{
+		{prefs: []string{"de-AT"}, want: "de", wantConf: language.High},
+		{prefs: []string{"da, fr-CH;q=0.8, en;q=0.5"}, want: "fr-FR", wantConf: language.High},
+		{prefs: []string{"pt-br"}, want: "pt-PT", wantConf: language.High},
+		{prefs: []string{"ja"}, want: "en-US", wantConf: language.No},
+		// languages are returned as the catalog names them, so Trnl finds them.
+		{prefs: []string{"de-CH"}, want: "de_CH", wantConf: language.Exact},
+		{prefs: []string{"zh-Hant-TW"}, want: "zh-hant-tw", wantConf: language.Exact},
+		{prefs: []string{"!!"}, want: "en-US", wantConf: language.No},
+	}
+	for _, tc := range tests {
+		lang, conf := c.Match(tc.prefs...)
+		if lang != tc.want || conf != tc.wantConf {
+			t.Errorf("Match(%q) = %s, %s; want %s, %s", tc.prefs, lang, conf, tc.want, tc.wantConf)
+		}
+		if got := c.Trnl(lang, "mod.go:1"); got != tc.want {
+			t.Errorf("Trnl(Match(%q)) = %q, want %q", tc.prefs, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package loc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFiles are the names a project configuration file is looked up as, in order.
+var ConfigFiles = []string{"goloc.yaml", "goloc.yml", "goloc.toml"}
+
+// Config is a project configuration file, usually kept at the module root. Every setting is optional, and is
+// overridden by the matching command line flag.
+type Config struct {
+	// DefaultLang is the language strings are extracted into, and which the others are checked against.
+	DefaultLang string `yaml:"default_lang" toml:"default_lang"`
+	// Langs are the target languages, created by goloc create if missing.
+	Langs       []string `yaml:"langs" toml:"langs"`
+	Funcs       []string `yaml:"funcs" toml:"funcs"`
+	Fmtfuncs    []string `yaml:"fmtfuncs" toml:"fmtfuncs"`
+	Pluralfuncs []string `yaml:"pluralfuncs" toml:"pluralfuncs"`
+	// TransDir is relative to the directory of the configuration file.
+	TransDir    string `yaml:"trans_dir" toml:"trans_dir"`
+	FileFormat  string `yaml:"file_format" toml:"file_format"`
+	KeyStrategy string `yaml:"key_strategy" toml:"key_strategy"`
+	// LangExprs are the expressions extract sets lang to; see Locer.LangExprs.
+	LangExprs     []string `yaml:"lang_exprs" toml:"lang_exprs"`
+	UseContext    bool     `yaml:"use_context" toml:"use_context"`
+	ICU           bool     `yaml:"icu" toml:"icu"`
+	FormatNumbers bool     `yaml:"format_numbers" toml:"format_numbers"`
+	Include       []string `yaml:"include" toml:"include"`
+	Exclude       []string `yaml:"exclude" toml:"exclude"`
+	Tags          []string `yaml:"tags" toml:"tags"`
+	Check         struct {
+		// FailOn is the lowest severity making goloc check fail, or "none".
+		FailOn string `yaml:"fail_on" toml:"fail_on"`
+		// Rules overrides the severity of check rules, by name; "off" drops their findings.
+		Rules map[string]string `yaml:"rules" toml:"rules"`
+	} `yaml:"check" toml:"check"`
+
+	// Path is the file the configuration was loaded from.
+	Path string `yaml:"-" toml:"-"`
+}
+
+// FindConfig looks for a configuration file in dir and its parents, up to the module root holding go.mod. It
+// returns "" if there is none.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range ConfigFiles {
+			p := filepath.Join(dir, name)
+			if _, err := os.Stat(p); err == nil {
+				return p, nil
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig reads the YAML or TOML configuration file at path. Unknown settings are an error, so typos don't go
+// unnoticed.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	switch filepath.Ext(path) {
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("%s: unknown setting '%s'", path, undecoded[0])
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown configuration file type, expected one of %s", path, strings.Join(ConfigFiles, ", "))
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if c.TransDir != "" && !filepath.IsAbs(c.TransDir) {
+		c.TransDir = moduleName(filepath.Join(filepath.Dir(path), c.TransDir))
+	}
+	c.Path = path
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	if c.FileFormat != "" {
+		if _, err := FileFormatByName(c.FileFormat); err != nil {
+			return err
+		}
+	}
+	if err := ValidateKeyStrategy(c.KeyStrategy); err != nil {
+		return err
+	}
+	if err := ValidateLangExprs(c.LangExprs); err != nil {
+		return err
+	}
+	if c.Check.FailOn != "" && c.Check.FailOn != "none" {
+		if _, err := ParseSeverity(c.Check.FailOn); err != nil {
+			return err
+		}
+	}
+	for rule, severity := range c.Check.Rules {
+		if _, ok := ruleInfo[rule]; !ok {
+			return fmt.Errorf("unknown check rule '%s'", rule)
+		}
+		if severity == "off" {
+			continue
+		}
+		if _, err := ParseSeverity(severity); err != nil {
+			return fmt.Errorf("check rule '%s': %w", rule, err)
+		}
+	}
+	return nil
+}
+
+// Apply sets the settings of c on l, except those whose flag, as named on the command line, has been changed.
+func (c *Config) Apply(l *Locer, changed func(flag string) bool) {
+	setString := func(flag string, dst *string, v string) {
+		if v != "" && !changed(flag) {
+			*dst = v
+		}
+	}
+	setBool := func(flag string, dst *bool, v bool) {
+		if v && !changed(flag) {
+			*dst = v
+		}
+	}
+	setSlice := func(flag string, dst *[]string, v []string) {
+		if len(v) > 0 && !changed(flag) {
+			*dst = v
+		}
+	}
+	setFuncs := func(flag string, dst *map[string]struct{}, v []string) {
+		if len(v) == 0 || changed(flag) {
+			return
+		}
+		*dst = make(map[string]struct{}, len(v))
+		for _, f := range v {
+			(*dst)[f] = struct{}{}
+		}
+	}
+
+	setString("lang", &l.DefaultLang, c.DefaultLang)
+	setFuncs("funcs", &l.Funcs, c.Funcs)
+	setFuncs("fmtfuncs", &l.Fmtfuncs, c.Fmtfuncs)
+	setFuncs("pluralfuncs", &l.Pluralfuncs, c.Pluralfuncs)
+	setString("trans-dir", &l.TransDir, c.TransDir)
+	setString("file-format", &l.FileFormat, c.FileFormat)
+	setString("key-strategy", &l.KeyStrategy, c.KeyStrategy)
+	setSlice("lang-expr", &l.LangExprs, c.LangExprs)
+	setBool("use-context", &l.UseContext, c.UseContext)
+	setBool("icu", &l.ICU, c.ICU)
+	setBool("format-numbers", &l.FormatNumbers, c.FormatNumbers)
+	setSlice("include", &l.Include, c.Include)
+	setSlice("exclude", &l.Exclude, c.Exclude)
+	setSlice("tags", &l.Tags, c.Tags)
+	if len(c.Check.Rules) > 0 {
+		l.Rules = c.Check.Rules
+	}
+}
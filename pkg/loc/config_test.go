@@ -0,0 +1,149 @@
+package loc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	files := map[string]string{
+		"goloc.yaml": `default_lang: en-US
+langs: [de-DE, fr-FR]
+fmtfuncs: [Sendf, "(*example.com/bot.Bot).Reply"]
+trans_dir: i18n
+file_format: json
+key_strategy: hash
+lang_exprs: ["s.lang(msg.From)", "getLang(u, \"en-GB\")"]
+check:
+  fail_on: warning
+  rules:
+    symbols: "off"
+    html: warning
+`,
+		"goloc.toml": `default_lang = "en-US"
+langs = ["de-DE", "fr-FR"]
+fmtfuncs = ["Sendf", "(*example.com/bot.Bot).Reply"]
+trans_dir = "i18n"
+file_format = "json"
+key_strategy = "hash"
+lang_exprs = ["s.lang(msg.From)", 'getLang(u, "en-GB")']
+
+[check]
+fail_on = "warning"
+rules = { symbols = "off", html = "warning" }
+`,
+	}
+	for name, data := range files {
+		dir := t.TempDir()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.DefaultLang != "en-US" || !reflect.DeepEqual(c.Langs, []string{"de-DE", "fr-FR"}) ||
+			len(c.Fmtfuncs) != 2 || c.FileFormat != "json" || c.KeyStrategy != KeyHash || c.Check.FailOn != "warning" {
+			t.Errorf("%s: unexpected config %+v", name, c)
+		}
+		if want := filepath.Join(dir, "i18n"); c.TransDir != want {
+			t.Errorf("%s: trans dir %s, want %s", name, c.TransDir, want)
+		}
+		if want := []string{"s.lang(msg.From)", `getLang(u, "en-GB")`}; !reflect.DeepEqual(c.LangExprs, want) {
+			t.Errorf("%s: lang exprs %q, want %q", name, c.LangExprs, want)
+		}
+		if !reflect.DeepEqual(c.Check.Rules, map[string]string{RuleSymbols: "off", RuleHTML: "warning"}) {
+			t.Errorf("%s: rules %v", name, c.Check.Rules)
+		}
+	}
+
+	bad := map[string]string{
+		"goloc.yaml": "default_language: en-US\n",
+		"goloc.toml": "[check.rules]\nsymbolz = \"off\"\n",
+		"goloc.yml":  "key_strategy: random\n",
+		"x.yaml":     "lang_exprs: [\"getLang(\"]\n",
+	}
+	for name, data := range bad {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("%s: no error loading %q", name, data)
+		}
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "mod", "bot", "cmd")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string) {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// above the module root, so not found.
+	write("goloc.yaml")
+	write("mod/go.mod")
+	if got, err := FindConfig(sub); err != nil || got != "" {
+		t.Errorf("FindConfig() = %q, %v; want none", got, err)
+	}
+
+	write("mod/goloc.toml")
+	if got, err := FindConfig(sub); err != nil || got != filepath.Join(dir, "mod", "goloc.toml") {
+		t.Errorf("FindConfig() = %q, %v; want the module's goloc.toml", got, err)
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	c := &Config{DefaultLang: "en-US", Funcs: []string{"Send"}, FileFormat: "yaml", Exclude: []string{"legacy"}}
+	c.Check.Rules = map[string]string{RuleSymbols: "off"}
+	l := &Locer{DefaultLang: DefaultLanguage, Funcs: map[string]struct{}{"Reply": {}}, FileFormat: "json"}
+	c.Apply(l, func(flag string) bool { return flag == "file-format" })
+
+	if l.DefaultLang != "en-US" || !reflect.DeepEqual(l.Funcs, map[string]struct{}{"Send": {}}) ||
+		!reflect.DeepEqual(l.Exclude, []string{"legacy"}) {
+		t.Errorf("config not applied: %+v", l)
+	}
+	if l.FileFormat != "json" {
+		t.Errorf("file format %s overrides the flag", l.FileFormat)
+	}
+
+	// the rules are used by check.
+	dir := t.TempDir()
+	tree := testTree()
+	tree["de-DE/bot/main.xml"] = Translation{Counter: 1, Rows: []Value{{Id: 1, Name: "bot/main.go:1", Value: "hallo {1} @"}}}
+	writeTestTree(t, dir, tree)
+	l = &Locer{DefaultLang: "en-GB", TransDir: dir, Rules: c.Check.Rules}
+	if err := l.Check("de-DE"); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range l.Diagnostics {
+		if d.Rule == RuleSymbols {
+			t.Errorf("symbols turned off, but reported: %s", d)
+		}
+	}
+}
+
+func TestKeyStrategy(t *testing.T) {
+	l := &Locer{KeyStrategy: KeyHash}
+	a, b := l.newKey("bot/main.go", 1, "hello"), l.newKey("bot/main.go", 2, "hello")
+	if a != b || !strings.HasPrefix(a, "bot/main.go:") || sourceModule(a) != "bot/main.go" {
+		t.Errorf("hash keys %s and %s should match, in module bot/main.go", a, b)
+	}
+	if a == l.newKey("bot/main.go", 1, "hi") {
+		t.Errorf("hash keys of different strings should differ")
+	}
+	l.KeyStrategy = ""
+	if got := l.newKey("bot/main.go", 3, "hello"); got != "bot/main.go:3" {
+		t.Errorf("counter key = %s, want bot/main.go:3", got)
+	}
+}
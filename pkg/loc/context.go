@@ -0,0 +1,60 @@
+package loc
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+)
+
+type langKey struct{}
+
+// WithLang returns a copy of ctx carrying tag, the language T, Tf, Tp and Tpf translate into; eg the result of
+// Match for a request.
+func WithLang(ctx context.Context, tag language.Tag) context.Context {
+	return context.WithValue(ctx, langKey{}, tag)
+}
+
+// LangFrom returns the language carried by ctx, or "" if there is none, which translates into the default
+// language.
+func LangFrom(ctx context.Context) string {
+	if tag, ok := ctx.Value(langKey{}).(language.Tag); ok {
+		return tag.String()
+	}
+	return ""
+}
+
+// T is Trnl in the language of ctx.
+func (c *Catalog) T(ctx context.Context, trnlVal string) string {
+	return c.Trnl(LangFrom(ctx), trnlVal)
+}
+
+// Tf is Trnlf in the language of ctx.
+func (c *Catalog) Tf(ctx context.Context, trnlVal string, dataMap map[string]string) string {
+	return c.Trnlf(LangFrom(ctx), trnlVal, dataMap)
+}
+
+// Tp is Trnp in the language of ctx.
+func (c *Catalog) Tp(ctx context.Context, trnlVal string, n int) string {
+	return c.Trnp(LangFrom(ctx), trnlVal, n)
+}
+
+// Tpf is Trnpf in the language of ctx.
+func (c *Catalog) Tpf(ctx context.Context, trnlVal string, n int, dataMap map[string]string) string {
+	return c.Trnpf(LangFrom(ctx), trnlVal, n, dataMap)
+}
+
+func T(ctx context.Context, trnlVal string) string {
+	return pkgCatalog().T(ctx, trnlVal)
+}
+
+func Tf(ctx context.Context, trnlVal string, dataMap map[string]string) string {
+	return pkgCatalog().Tf(ctx, trnlVal, dataMap)
+}
+
+func Tp(ctx context.Context, trnlVal string, n int) string {
+	return pkgCatalog().Tp(ctx, trnlVal, n)
+}
+
+func Tpf(ctx context.Context, trnlVal string, n int, dataMap map[string]string) string {
+	return pkgCatalog().Tpf(ctx, trnlVal, n, dataMap)
+}
@@ -0,0 +1,39 @@
+package loc
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestCatalogContext(t *testing.T) {
+	c := NewCatalog("en-GB")
+	c.add("en-GB", "mod.go", Translation{Rows: []Value{
+		{Id: 1, Name: "mod.go:1", Value: "hello {1}"},
+		{Id: 2, Name: "mod.go:2", Plurals: []Plural{{Form: "one", Value: "{1} file"}, {Form: "other", Value: "{1} files"}}},
+	}})
+	c.add("de-DE", "mod.go", Translation{Rows: []Value{
+		{Id: 1, Name: "mod.go:1", Value: "hallo {1}"},
+		{Id: 2, Name: "mod.go:2", Plurals: []Plural{{Form: "one", Value: "{1} Datei"}, {Form: "other", Value: "{1} Dateien"}}},
+	}})
+
+	ctx := context.Background()
+	if got := LangFrom(ctx); got != "" {
+		t.Errorf("LangFrom(background) = %q, want none", got)
+	}
+	if got := c.Tf(ctx, "mod.go:1", map[string]string{"1": "Ann"}); got != "hello Ann" {
+		t.Errorf("Tf without a language = %q, want the default language", got)
+	}
+
+	ctx = WithLang(ctx, language.MustParse("de-DE"))
+	if got := LangFrom(ctx); got != "de-DE" {
+		t.Errorf("LangFrom() = %q, want de-DE", got)
+	}
+	if got := c.T(ctx, "mod.go:1"); got != "hallo {1}" {
+		t.Errorf("T() = %q, want hallo {1}", got)
+	}
+	if got := c.Tpf(ctx, "mod.go:2", 3, map[string]string{"1": "3"}); got != "3 Dateien" {
+		t.Errorf("Tpf() = %q, want 3 Dateien", got)
+	}
+}
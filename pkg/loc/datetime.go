@@ -0,0 +1,257 @@
+package loc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// dateSymbols are the CLDR date and time patterns of a language, by style, along with the names they use. x/text
+// has no date data, so the patterns are kept here for the common languages.
+type dateSymbols struct {
+	date, time          map[string]string // style:pattern, for short, medium, long and full
+	months, shortMonths []string          // nil if months are always numeric
+	days, shortDays     []string          // from Sunday
+	am, pm              string
+}
+
+var (
+	enDays      = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	enShortDays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	enMonths    = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September",
+		"October", "November", "December"}
+	enShortMonths = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+	// times24 are the time patterns of most languages using a 24 hour clock.
+	times24 = map[string]string{"short": "HH:mm", "medium": "HH:mm:ss", "long": "HH:mm:ss z", "full": "HH:mm:ss zzzz"}
+)
+
+// dateLocales holds the dateSymbols by base language; en is American English, other English regions use en-GB.
+var dateLocales = map[string]*dateSymbols{
+	"en": {
+		date:   map[string]string{"short": "M/d/yy", "medium": "MMM d, y", "long": "MMMM d, y", "full": "EEEE, MMMM d, y"},
+		time:   map[string]string{"short": "h:mm a", "medium": "h:mm:ss a", "long": "h:mm:ss a z", "full": "h:mm:ss a zzzz"},
+		months: enMonths, shortMonths: enShortMonths, days: enDays, shortDays: enShortDays,
+		am: "AM", pm: "PM",
+	},
+	"en-GB": {
+		date:   map[string]string{"short": "dd/MM/y", "medium": "d MMM y", "long": "d MMMM y", "full": "EEEE d MMMM y"},
+		time:   times24,
+		months: enMonths, shortMonths: enShortMonths, days: enDays, shortDays: enShortDays,
+		am: "am", pm: "pm",
+	},
+	"de": {
+		date: map[string]string{"short": "dd.MM.yy", "medium": "dd.MM.y", "long": "d. MMMM y", "full": "EEEE, d. MMMM y"},
+		time: times24,
+		months: []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober",
+			"November", "Dezember"},
+		shortMonths: []string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		days:        []string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   []string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		am:          "AM", pm: "PM",
+	},
+	"fr": {
+		date: map[string]string{"short": "dd/MM/y", "medium": "d MMM y", "long": "d MMMM y", "full": "EEEE d MMMM y"},
+		time: times24,
+		months: []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre",
+			"novembre", "décembre"},
+		shortMonths: []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   []string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		am:          "AM", pm: "PM",
+	},
+	"es": {
+		date: map[string]string{"short": "d/M/yy", "medium": "d MMM y", "long": "d 'de' MMMM 'de' y",
+			"full": "EEEE, d 'de' MMMM 'de' y"},
+		time: map[string]string{"short": "H:mm", "medium": "H:mm:ss", "long": "H:mm:ss z", "full": "H:mm:ss (zzzz)"},
+		months: []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre",
+			"octubre", "noviembre", "diciembre"},
+		shortMonths: []string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        []string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   []string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		am:          "a. m.", pm: "p. m.",
+	},
+	"it": {
+		date: map[string]string{"short": "dd/MM/yy", "medium": "d MMM y", "long": "d MMMM y", "full": "EEEE d MMMM y"},
+		time: times24,
+		months: []string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre",
+			"ottobre", "novembre", "dicembre"},
+		shortMonths: []string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:        []string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays:   []string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		am:          "AM", pm: "PM",
+	},
+	"pt": {
+		date: map[string]string{"short": "dd/MM/y", "medium": "d 'de' MMM 'de' y", "long": "d 'de' MMMM 'de' y",
+			"full": "EEEE, d 'de' MMMM 'de' y"},
+		time: times24,
+		months: []string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro",
+			"outubro", "novembro", "dezembro"},
+		shortMonths: []string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		days: []string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira",
+			"sábado"},
+		shortDays: []string{"dom.", "seg.", "ter.", "qua.", "qui.", "sex.", "sáb."},
+		am:        "AM", pm: "PM",
+	},
+	"nl": {
+		date: map[string]string{"short": "dd-MM-y", "medium": "d MMM y", "long": "d MMMM y", "full": "EEEE d MMMM y"},
+		time: times24,
+		months: []string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september",
+			"oktober", "november", "december"},
+		shortMonths: []string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:        []string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays:   []string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		am:          "a.m.", pm: "p.m.",
+	},
+	"ru": {
+		date: map[string]string{"short": "dd.MM.y", "medium": "d MMM y 'г'.", "long": "d MMMM y 'г'.",
+			"full": "EEEE, d MMMM y 'г'."},
+		time: times24,
+		months: []string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября",
+			"ноября", "декабря"},
+		shortMonths: []string{"янв.", "февр.", "мар.", "апр.", "мая", "июн.", "июл.", "авг.", "сент.", "окт.", "нояб.", "дек."},
+		days:        []string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		shortDays:   []string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"},
+		am:          "AM", pm: "PM",
+	},
+	"ja": {
+		date:      map[string]string{"short": "y/MM/dd", "medium": "y/MM/dd", "long": "y年M月d日", "full": "y年M月d日EEEE"},
+		time:      map[string]string{"short": "H:mm", "medium": "H:mm:ss", "long": "H:mm:ss z", "full": "H時mm分ss秒 zzzz"},
+		days:      []string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		shortDays: []string{"日", "月", "火", "水", "木", "金", "土"},
+		am:        "午前", pm: "午後",
+	},
+	"zh": {
+		date:      map[string]string{"short": "y/M/d", "medium": "y年M月d日", "long": "y年M月d日", "full": "y年M月d日EEEE"},
+		time:      map[string]string{"short": "HH:mm", "medium": "HH:mm:ss", "long": "z HH:mm:ss", "full": "zzzz HH:mm:ss"},
+		days:      []string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		shortDays: []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+		am:        "上午", pm: "下午",
+	},
+}
+
+// isoDates are used for languages without dateSymbols, rather than another language's names and order.
+var isoDates = &dateSymbols{
+	date: map[string]string{"short": "y-MM-dd", "medium": "y-MM-dd", "long": "y-MM-dd", "full": "y-MM-dd"},
+	time: times24,
+}
+
+func dateSymbolsFor(tag language.Tag) *dateSymbols {
+	base, _ := tag.Base()
+	if base.String() == "en" {
+		if region, _ := tag.Region(); region.String() != "US" {
+			return dateLocales["en-GB"]
+		}
+	}
+	if s, ok := dateLocales[base.String()]; ok {
+		return s
+	}
+	return isoDates
+}
+
+// formatTime formats t as a date or time of the given style (short, medium, long or full; medium by default) with
+// the CLDR pattern of tag. Time zones are given by their abbreviation.
+func formatTime(tag language.Tag, typ string, style string, t time.Time) string {
+	s := dateSymbolsFor(tag)
+	patterns := s.date
+	if typ == "time" {
+		patterns = s.time
+	}
+	pattern, ok := patterns[style]
+	if !ok {
+		pattern = patterns["medium"]
+	}
+	return s.format(pattern, t)
+}
+
+// format formats t with a CLDR date pattern, such as "EEEE, d MMMM y"; text in single quotes is literal.
+func (s *dateSymbols) format(pattern string, t time.Time) string {
+	var sb strings.Builder
+	p := []rune(pattern)
+	for i := 0; i < len(p); {
+		c := p[i]
+		if c == '\'' {
+			end := i + 1
+			for end < len(p) && p[end] != '\'' {
+				end++
+			}
+			if end == i+1 {
+				sb.WriteRune('\'') // '' is a quote
+			}
+			sb.WriteString(string(p[i+1 : min(end, len(p))]))
+			i = end + 1
+			continue
+		}
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			sb.WriteRune(c)
+			i++
+			continue
+		}
+		n := 1
+		for i+n < len(p) && p[i+n] == c {
+			n++
+		}
+		i += n
+		sb.WriteString(s.field(c, n, t))
+	}
+	return sb.String()
+}
+
+// field formats the pattern field of n letters c.
+func (s *dateSymbols) field(c rune, n int, t time.Time) string {
+	pad := func(v int) string {
+		out := strconv.Itoa(v)
+		for len(out) < n {
+			out = "0" + out
+		}
+		return out
+	}
+	name := func(v int, short, long []string) string {
+		switch {
+		case n == 4 && long != nil:
+			return long[v]
+		case short != nil:
+			return short[v]
+		}
+		return strconv.Itoa(v + 1)
+	}
+	switch c {
+	case 'y':
+		if n == 2 {
+			return pad(t.Year() % 100)
+		}
+		return pad(t.Year())
+	case 'M', 'L':
+		if n >= 3 {
+			return name(int(t.Month())-1, s.shortMonths, s.months)
+		}
+		return pad(int(t.Month()))
+	case 'd':
+		return pad(t.Day())
+	case 'E':
+		return name(int(t.Weekday()), s.shortDays, s.days)
+	case 'a':
+		if t.Hour() < 12 {
+			return s.am
+		}
+		return s.pm
+	case 'h':
+		h := t.Hour() % 12
+		if h == 0 {
+			h = 12
+		}
+		return pad(h)
+	case 'H':
+		return pad(t.Hour())
+	case 'm':
+		return pad(t.Minute())
+	case 's':
+		return pad(t.Second())
+	case 'z':
+		zone, _ := t.Zone()
+		return zone
+	}
+	return strings.Repeat(string(c), n)
+}
@@ -0,0 +1,243 @@
+package loc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+)
+
+// Severity is how serious a Diagnostic is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// ParseSeverity returns the Severity called name.
+func ParseSeverity(name string) (Severity, error) {
+	for i, n := range severityNames {
+		if n == name {
+			return Severity(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity '%s'", name)
+}
+
+// Check rules, as reported in Diagnostic.Rule.
+const (
+	RuleKeyMismatch = "key-mismatch"
+	RuleIdMismatch  = "id-mismatch"
+	RuleICUSyntax   = "icu-syntax"
+	RuleICUArgs     = "icu-args"
+	RuleSelect      = "select"
+	RuleCurlies     = "curlies"
+	RuleHTML        = "html"
+	RuleSymbols     = "symbols"
+)
+
+// ruleInfo describes each rule, and the severity it is reported with.
+var ruleInfo = map[string]struct {
+	severity Severity
+	desc     string
+}{
+	RuleKeyMismatch: {SeverityError, "Entry is stored under a different key than its name"},
+	RuleIdMismatch:  {SeverityError, "Entry has a different id than in the default language"},
+	RuleICUSyntax:   {SeverityError, "Value is not a valid ICU MessageFormat message"},
+	RuleICUArgs:     {SeverityError, "ICU arguments differ from the default language"},
+	RuleSelect:      {SeverityError, "Select cases differ from the default language"},
+	RuleCurlies:     {SeverityError, "Placeholders differ from the default language"},
+	RuleHTML:        {SeverityError, "Value contains invalid HTML"},
+	RuleSymbols:     {SeverityWarning, "Special symbols differ from the default language"},
+}
+
+// Diagnostic is a single problem found when checking a language.
+type Diagnostic struct {
+	Lang     string   `json:"lang"`
+	Key      string   `json:"key"`
+	Form     string   `json:"form,omitempty"` // plural form or select case, if the problem is in one
+	File     string   `json:"file,omitempty"` // translation file holding the key
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	key := "'" + d.Key + "'"
+	if d.Form != "" {
+		key += " (" + d.Form + ")"
+	}
+	return fmt.Sprintf("%s: %s: %s\t%s: %s", d.Severity, d.Lang, key, d.Rule, d.Message)
+}
+
+// report records a check problem found in lang, with the severity of rule, unless Rules turns it off.
+func (l *Locer) report(lang string, key string, form string, rule string, err error) {
+	severity := ruleInfo[rule].severity
+	if override, ok := l.Rules[rule]; ok {
+		if override == "off" {
+			return
+		}
+		if s, err := ParseSeverity(override); err == nil {
+			severity = s
+		}
+	}
+	l.Diagnostics = append(l.Diagnostics, Diagnostic{
+		Lang:     lang,
+		Key:      key,
+		Form:     form,
+		File:     filepath.Join(l.transDir(), moduleFile(l.fileFormat(), lang, sourceModule(key))),
+		Rule:     rule,
+		Severity: severity,
+		Message:  err.Error(),
+	})
+}
+
+// MaxSeverity returns the highest severity among diags, and false if there are none.
+func MaxSeverity(diags []Diagnostic) (Severity, bool) {
+	var worst Severity
+	for _, d := range diags {
+		if d.Severity > worst {
+			worst = d.Severity
+		}
+	}
+	return worst, len(diags) > 0
+}
+
+// WriteDiagnostics writes diags as plain text ("text"), a JSON array ("json") or a SARIF 2.1.0 log ("sarif").
+func WriteDiagnostics(w io.Writer, format string, diags []Diagnostic) error {
+	switch format {
+	case "text":
+		for _, d := range diags {
+			if _, err := fmt.Fprintln(w, d.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if diags == nil {
+			diags = []Diagnostic{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diags)
+	case "sarif":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sarifLog(diags))
+	default:
+		return fmt.Errorf("unknown diagnostics format '%s'", format)
+	}
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+func sarifLevel(s Severity) string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func sarifLog(diags []Diagnostic) any {
+	ids := make([]string, 0, len(ruleInfo))
+	for id := range ruleInfo {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rules := make([]sarifRule, 0, len(ids))
+	for _, id := range ids {
+		r := sarifRule{ID: id, ShortDescription: sarifMessage{Text: ruleInfo[id].desc}}
+		r.DefaultConfig.Level = sarifLevel(ruleInfo[id].severity)
+		rules = append(rules, r)
+	}
+
+	results := make([]sarifResult, 0, len(diags))
+	for _, d := range diags {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(d.File)
+		name := d.Lang + "/" + d.Key
+		if d.Form != "" {
+			name += "/" + d.Form
+		}
+		loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: name}}
+		results = append(results, sarifResult{
+			RuleID:    d.Rule,
+			Level:     sarifLevel(d.Severity),
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	type driver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	type run struct {
+		Tool struct {
+			Driver driver `json:"driver"`
+		} `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	r := run{Results: results}
+	r.Tool.Driver = driver{Name: "goloc", InformationURI: "https://" + runtimeImport, Rules: rules}
+	return struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []run  `json:"runs"`
+	}{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []run{r},
+	}
+}
@@ -0,0 +1,84 @@
+package loc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestCheckDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	tree := testTree()
+	// each plural form is checked against the default's matching form, or "other" if the default has none.
+	tree["en-GB/bot/main.xml"] = Translation{Counter: 4, Rows: append(tree["en-GB/bot/main.xml"].Rows,
+		Value{Id: 4, Name: "bot/main.go:4", Plurals: []Plural{{Form: "one", Value: "<x>one file"}, {Form: "other", Value: "{1} files"}}},
+	)}
+	tree["de-DE/bot/main.xml"] = Translation{Counter: 4, Rows: []Value{
+		{Id: 1, Name: "bot/main.go:1", Value: "hallo {2} @"},
+		{Id: 2, Name: "bot/main.go:2", Plurals: []Plural{{Form: "one", Value: "eine Datei"}, {Form: "other", Value: "<b>{1} Dateien"}}},
+		{Id: 7, Name: "bot/main.go:3", Select: &Select{Arg: "g", Cases: []Case{{Key: "male", Value: "er"}, {Key: "other"}}}},
+		{Id: 4, Name: "bot/main.go:4", Plurals: []Plural{{Form: "one", Value: "<x>eine Datei"}, {Form: "many", Value: "Dateien"}, {Form: "other", Value: "{1} Dateien"}}},
+	}}
+	writeTestTree(t, dir, tree)
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+	if err := l.Check("de-DE"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range l.Diagnostics {
+		got = append(got, d.Key+" "+d.Form+" "+d.Rule+" "+d.Severity.String())
+	}
+	want := []string{
+		"bot/main.go:1  curlies error",
+		"bot/main.go:1  symbols warning",
+		"bot/main.go:2 other html error",
+		"bot/main.go:3  id-mismatch error",
+		"bot/main.go:4 many curlies error",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if worst, ok := MaxSeverity(l.Diagnostics); !ok || worst != SeverityError {
+		t.Errorf("MaxSeverity() = %v, %v; want error", worst, ok)
+	}
+
+	var sb strings.Builder
+	if err := WriteDiagnostics(&sb, "sarif", l.Diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &sarif); err != nil {
+		t.Fatal(err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != len(want) {
+		t.Fatalf("unexpected SARIF log:\n%s", sb.String())
+	}
+	if r := sarif.Runs[0].Results[1]; r.RuleID != RuleSymbols || r.Level != "warning" {
+		t.Errorf("result 1 = %+v, want a symbols warning", r)
+	}
+}
+
+func TestCheckAfterCreate(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, testTree())
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+	l.Create(nil, language.MustParse("fr-FR"))
+	if err := l.Check("fr-FR"); err != nil {
+		t.Fatal(err)
+	}
+	// untranslated placeholders aren't errors.
+	for _, d := range l.Diagnostics {
+		t.Errorf("unexpected diagnostic on a fresh placeholder: %s %s %s: %s", d.Key, d.Form, d.Rule, d.Message)
+	}
+}
@@ -0,0 +1,327 @@
+package loc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// moduleSet is a single module of the translation tree, in the default language and every language which has it.
+type moduleSet struct {
+	name  string // relative to the language directory, eg bot/main.xml
+	def   Translation
+	langs map[string]Translation
+}
+
+// loadModuleSets reads every module of the default language, along with the same module in every other language.
+// The other languages are returned sorted.
+func (l *Locer) loadModuleSets() ([]moduleSet, []string, error) {
+	fsys := os.DirFS(l.transDir())
+	format := l.fileFormat()
+	dirs, err := langDirs(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+	var langs []string
+	for _, lang := range dirs {
+		if lang != l.DefaultLang {
+			langs = append(langs, lang)
+		}
+	}
+
+	var sets []moduleSet
+	err = walkModules(fsys, l.DefaultLang, func(name string) error {
+		def, err := readModule(fsys, format, l.DefaultLang, name)
+		if err != nil {
+			return err
+		}
+		set := moduleSet{name: name, def: def, langs: make(map[string]Translation)}
+		for _, lang := range langs {
+			t, err := readModule(fsys, format, lang, name)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			set.langs[lang] = t
+		}
+		sets = append(sets, set)
+		return nil
+	})
+	return sets, langs, err
+}
+
+// rows returns the rows of lang in the order of the default language. Rows lang doesn't have yet are returned
+// untranslated.
+func (m moduleSet) rows(lang string) []Value {
+	have := make(map[string]Value)
+	for _, row := range m.langs[lang].Rows {
+		if row.Name != "" {
+			have[row.Name] = row
+		}
+	}
+	out := make([]Value, 0, len(m.def.Rows))
+	for _, def := range m.def.Rows {
+		if v, ok := have[def.Name]; ok {
+			out = append(out, v)
+			continue
+		}
+		if def.Name == "" {
+			out = append(out, def)
+			continue
+		}
+		out = append(out, untranslated(lang, def))
+	}
+	return out
+}
+
+// sourceModule returns the source file a key was extracted from; keys are of the form file.go:id.
+func sourceModule(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// unitID identifies a single plural form or select case of a value in the exchange formats, which only handle
+// plain strings. Plain values use their name.
+func unitID(name string, variant string) string {
+	if variant == "" {
+		return name
+	}
+	return name + "|" + variant
+}
+
+// unit is a single translatable string of a value: the value itself, or one of its plural forms or select cases.
+type unit struct {
+	id     string
+	source string
+	target string
+}
+
+// units splits def and its translation cur into plain strings, using the plural forms of lang.
+func units(def Value, cur Value, lang string) []unit {
+	switch {
+	case len(def.Plurals) > 0:
+		var out []unit
+		for _, f := range pluralForms(lang) {
+			src := def.Plural(f)
+			if src == "" {
+				src = def.Plural("other")
+			}
+			out = append(out, unit{id: unitID(def.Name, f), source: src, target: cur.Plural(f)})
+		}
+		return out
+	case def.Select != nil:
+		var out []unit
+		for _, c := range def.Select.Cases {
+			u := unit{id: unitID(def.Name, c.Key), source: c.Value}
+			if cur.Select != nil {
+				u.target = cur.Select.Case(c.Key)
+			}
+			out = append(out, u)
+		}
+		return out
+	default:
+		return []unit{{id: def.Name, source: def.Value, target: cur.Value}}
+	}
+}
+
+// unitState returns the translation state of a unit of v with the given target text.
+func unitState(v Value, target string) string {
+	switch {
+	case target == "":
+		return StateNew
+	case v.State == StateReviewed:
+		return StateReviewed
+	default:
+		return StateTranslated
+	}
+}
+
+// translatorComment returns the comment translators left on cur, or an empty string if it is still the generated one.
+func translatorComment(lang string, def Value, cur Value) string {
+	if cur.Comment == untranslated(lang, def).Comment {
+		return ""
+	}
+	return cur.Comment
+}
+
+// importedUnit is a translated unit read from an exchange file.
+type importedUnit struct {
+	text    string
+	state   string
+	comment string
+}
+
+// mergeUnits merges imported units, keyed by unitID, into lang. Empty units are skipped, and units matching no
+// value are reported.
+func (l *Locer) mergeUnits(file string, lang string, imported map[string]importedUnit) error {
+	if lang == l.DefaultLang {
+		return fmt.Errorf("refusing to import into the default language %s", lang)
+	}
+	used := make(map[string]struct{})
+	err := l.mergeTranslations(lang, func(v Value) (Value, bool) {
+		changed, translated, reviewed := false, false, true
+		take := func(id string) (string, bool) {
+			u, ok := imported[id]
+			if !ok {
+				return "", false
+			}
+			used[id] = struct{}{}
+			if u.comment != "" && u.comment != v.Comment {
+				v.Comment = u.comment
+				changed = true
+			}
+			if u.text == "" {
+				return "", false
+			}
+			translated = true
+			reviewed = reviewed && u.state == StateReviewed
+			return u.text, true
+		}
+
+		switch {
+		case len(v.Plurals) > 0:
+			v.Plurals = slices.Clone(v.Plurals)
+			for _, p := range pluralFormNames {
+				if text, ok := take(unitID(v.Name, p.name)); ok {
+					v.Plurals = setPlural(v.Plurals, p.name, text)
+				}
+			}
+		case v.Select != nil:
+			v.Select = &Select{Arg: v.Select.Arg, Cases: slices.Clone(v.Select.Cases)}
+			for i, c := range v.Select.Cases {
+				if text, ok := take(unitID(v.Name, c.Key)); ok {
+					v.Select.Cases[i].Value = text
+				}
+			}
+		default:
+			if text, ok := take(v.Name); ok {
+				v.Value = text
+			}
+		}
+		if translated {
+			v.State = ""
+			if reviewed {
+				v.State = StateReviewed
+			}
+		}
+		return v, changed || translated
+	})
+	if err != nil {
+		return err
+	}
+
+	for id := range imported {
+		if _, ok := used[id]; !ok {
+			Logger.Warn().Msgf("%s: ignoring unknown message '%s'", file, id)
+		}
+	}
+	return nil
+}
+
+func setPlural(plurals []Plural, form string, text string) []Plural {
+	for i, p := range plurals {
+		if p.Form == form {
+			plurals[i].Value = text
+			return plurals
+		}
+	}
+	return append(plurals, Plural{Form: form, Value: text})
+}
+
+// Export writes the translation tree to outDir in the given exchange format, to be handed to translators: "po" for
+// gettext, or "xliff" and "xliff2" for XLIFF 1.2 and 2.0.
+func (l *Locer) Export(format string, outDir string) error {
+	sets, langs, err := l.loadModuleSets()
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("no translations found for default language %s in %s", l.DefaultLang, l.transDir())
+	}
+	switch format {
+	case "po":
+		return l.exportPO(sets, langs, outDir)
+	case "xliff", "xliff12":
+		return l.exportXLIFF(sets, langs, outDir, false)
+	case "xliff2":
+		return l.exportXLIFF(sets, langs, outDir, true)
+	default:
+		return fmt.Errorf("unknown export format '%s'", format)
+	}
+}
+
+// Import merges translated exchange files back into the translation tree. Rows keep the ids and order of the
+// default language. If format is empty, it is guessed from each file's extension.
+func (l *Locer) Import(format string, files []string) error {
+	for _, file := range files {
+		f := format
+		if f == "" {
+			f = strings.TrimPrefix(filepath.Ext(file), ".")
+		}
+		var err error
+		switch f {
+		case "po":
+			err = l.importPO(file)
+		case "xliff", "xliff12", "xliff2", "xlf":
+			// both versions are told apart by their namespace
+			err = l.importXLIFF(file)
+		default:
+			err = fmt.Errorf("unknown import format '%s'", f)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// mergeTranslations calls apply on every row of lang, in the order of the default language, and writes back each
+// module with a changed row. Ids and names always follow the default language.
+func (l *Locer) mergeTranslations(lang string, apply func(v Value) (Value, bool)) error {
+	sets, _, err := l.loadModuleSets()
+	if err != nil {
+		return err
+	}
+	for _, set := range sets {
+		rows := set.rows(lang)
+		changed := false
+		for i, row := range rows {
+			if row.Name == "" {
+				continue
+			}
+			v, ok := apply(row)
+			if !ok {
+				continue
+			}
+			v.Id = row.Id
+			v.Name = row.Name
+			rows[i] = v
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+
+		t := Translation{Rows: rows, Counter: set.def.Counter}
+		if old, ok := set.langs[lang]; ok && old.Counter > t.Counter {
+			t.Counter = old.Counter
+		}
+		if format := l.fileFormat(); l.Apply {
+			err = writeModuleFile(format, filepath.Join(l.transDir(), moduleFile(format, lang, set.name)), t)
+		} else {
+			err = format.Encode(os.Stdout, t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
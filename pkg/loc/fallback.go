@@ -0,0 +1,84 @@
+package loc
+
+import (
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+// SetFallbacks configures an explicit fallback chain for lang, such as pt-BR -> pt-PT. The given languages are tried,
+// in order, before the CLDR parents of lang.
+func (c *Catalog) SetFallbacks(lang string, fallbacks ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fallbacks == nil {
+		c.fallbacks = make(map[string][]string)
+	}
+	c.fallbacks[lang] = fallbacks
+	c.chains = nil
+}
+
+// FallbackChain returns every language Trnl will try for lang, in order; eg de-AT -> de -> en-US.
+func (c *Catalog) FallbackChain(lang string) []string {
+	return slices.Clone(c.chain(lang))
+}
+
+func (c *Catalog) chain(lang string) []string {
+	c.mu.RLock()
+	chain, ok := c.chains[lang]
+	c.mu.RUnlock()
+	if ok {
+		return chain
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[string]struct{})
+	chain = c.buildChain(lang, nil, seen)
+	chain = c.buildChain(c.defaultLang, chain, seen)
+	// only languages the catalog knows of are cached, as lang may come from a request, so the cache stays bounded.
+	if _, loaded := c.data[lang]; loaded || c.fallbacks[lang] != nil || lang == c.defaultLang {
+		if c.chains == nil {
+			c.chains = make(map[string][]string)
+		}
+		c.chains[lang] = chain
+	}
+	return chain
+}
+
+// buildChain appends lang, its explicit fallbacks and its CLDR parents to chain. Must be called with c.mu held.
+func (c *Catalog) buildChain(lang string, chain []string, seen map[string]struct{}) []string {
+	if lang == "" {
+		return chain
+	}
+	if _, ok := seen[lang]; ok {
+		return chain
+	}
+	seen[lang] = struct{}{}
+	chain = append(chain, lang)
+
+	for _, f := range c.fallbacks[lang] {
+		chain = c.buildChain(f, chain, seen)
+	}
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return chain
+	}
+	if canon := tag.String(); canon != lang {
+		// directory names aren't always canonical (eg de_AT); still try the canonical form.
+		chain = c.buildChain(canon, chain, seen)
+	}
+	if parent := tag.Parent(); parent != language.Und {
+		chain = c.buildChain(parent.String(), chain, seen)
+	}
+	return chain
+}
+
+func SetFallbacks(lang string, fallbacks ...string) {
+	pkgCatalog().SetFallbacks(lang, fallbacks...)
+}
+
+func FallbackChain(lang string) []string {
+	return pkgCatalog().FallbackChain(lang)
+}
@@ -0,0 +1,122 @@
+package loc
+
+import (
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// skipReason returns why the source file name should not be handled, or "" if it should. Files are skipped if they
+// are tests (unless Tests is set), fail the Include and Exclude globs, or are excluded by their build constraints
+// for GOOS, GOARCH and Tags.
+func (l *Locer) skipReason(name string) string {
+	slashed := strings.TrimPrefix(filepath.ToSlash(moduleName(name)), "./")
+	if !l.Tests && strings.HasSuffix(slashed, "_test.go") {
+		return "test file"
+	}
+	for _, glob := range l.Exclude {
+		if matchGlob(glob, slashed) {
+			return "excluded by " + glob
+		}
+	}
+	if len(l.Include) > 0 {
+		included := false
+		for _, glob := range l.Include {
+			included = included || matchGlob(glob, slashed)
+		}
+		if !included {
+			return "not included"
+		}
+	}
+	ok, err := l.buildContext().MatchFile(filepath.Dir(name), filepath.Base(name))
+	if err != nil {
+		return "unreadable build constraints: " + err.Error()
+	}
+	if !ok {
+		return "excluded by build constraints"
+	}
+	return ""
+}
+
+// skip records that name was not handled, and why.
+func (l *Locer) skip(name string, reason string) {
+	Logger.Debug().Msgf("skipping %s: %s", name, reason)
+	if l.Skipped == nil {
+		l.Skipped = make(map[string]string)
+	}
+	l.Skipped[name] = reason
+}
+
+// buildContext returns the context build constraints are evaluated in: the current platform, unless overridden by
+// GOOS and GOARCH, with the extra Tags.
+func (l *Locer) buildContext() *build.Context {
+	ctx := build.Default
+	if l.GOOS != "" {
+		ctx.GOOS = l.GOOS
+	}
+	if l.GOARCH != "" {
+		ctx.GOARCH = l.GOARCH
+	}
+	ctx.BuildTags = l.Tags
+	return &ctx
+}
+
+// buildFlags returns the go command flags and environment matching buildContext, for loading packages. env is nil
+// if the current environment is used as is.
+func (l *Locer) buildFlags() (flags []string, env []string) {
+	if len(l.Tags) > 0 {
+		flags = append(flags, "-tags="+strings.Join(l.Tags, ","))
+	}
+	if l.GOOS != "" || l.GOARCH != "" {
+		env = os.Environ()
+		if l.GOOS != "" {
+			env = append(env, "GOOS="+l.GOOS)
+		}
+		if l.GOARCH != "" {
+			env = append(env, "GOARCH="+l.GOARCH)
+		}
+	}
+	return flags, env
+}
+
+// matchGlob reports whether the slash separated file name matches glob. A glob without a slash matches any
+// element of name, so *_test.go matches test files and legacy matches everything in a legacy directory. Otherwise
+// glob matches name from the start, with ** matching any number of directories; a glob matching a directory
+// matches everything in it.
+func matchGlob(glob string, name string) bool {
+	glob = strings.TrimPrefix(strings.TrimSuffix(glob, "/"), "./")
+	elems := strings.Split(name, "/")
+	if !strings.Contains(glob, "/") && glob != "**" {
+		for _, elem := range elems {
+			if ok, _ := path.Match(glob, elem); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchElems(strings.Split(glob, "/"), elems)
+}
+
+// matchElems matches glob elements against a prefix of the name elements.
+func matchElems(glob []string, elems []string) bool {
+	if len(glob) == 0 {
+		return true // a directory of name matched
+	}
+	if glob[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchElems(glob[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(glob[0], elems[0]); !ok {
+		return false
+	}
+	return matchElems(glob[1:], elems[1:])
+}
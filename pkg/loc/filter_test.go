@@ -0,0 +1,117 @@
+package loc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		name string
+		want bool
+	}{
+		{glob: "*_test.go", name: "bot/main_test.go", want: true},
+		{glob: "*_test.go", name: "bot/main.go", want: false},
+		{glob: "legacy", name: "bot/legacy/old.go", want: true},
+		{glob: "legacy", name: "bot/legacyish/old.go", want: false},
+		{glob: "bot", name: "bot/main.go", want: true},
+		{glob: "bot/", name: "bot/main.go", want: true},
+		{glob: "./bot", name: "bot/main.go", want: true},
+		{glob: "bot/*.go", name: "bot/main.go", want: true},
+		{glob: "bot/*.go", name: "bot/sub/main.go", want: false},
+		{glob: "bot/**/*.go", name: "bot/sub/deep/main.go", want: true},
+		{glob: "bot/**/*.go", name: "bot/main.go", want: true},
+		{glob: "**/gen/*.go", name: "a/b/gen/x.go", want: true},
+		{glob: "cmd/tools/**", name: "cmd/tools/x/main.go", want: true},
+		{glob: "cmd/tools/**", name: "cmd/bot/main.go", want: false},
+	}
+	for _, tc := range tests {
+		if got := matchGlob(tc.glob, tc.name); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.glob, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":       "package main\n",
+		"main_test.go":  "package main\n",
+		"tool.go":       "//go:build ignore\n\npackage main\n",
+		"win.go":        "//go:build windows\n\npackage main\n",
+		"bot_darwin.go": "package main\n",
+		"legacy/old.go": "package legacy\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		name string
+		l    Locer
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			l:    Locer{GOOS: "linux"},
+			want: map[string]string{
+				"main.go":       "",
+				"main_test.go":  "test file",
+				"tool.go":       "excluded by build constraints",
+				"win.go":        "excluded by build constraints",
+				"bot_darwin.go": "excluded by build constraints",
+				"legacy/old.go": "",
+			},
+		},
+		{
+			name: "tags and platform",
+			l:    Locer{GOOS: "windows", Tags: []string{"ignore"}, Tests: true},
+			want: map[string]string{
+				"main_test.go":  "",
+				"tool.go":       "",
+				"win.go":        "",
+				"bot_darwin.go": "excluded by build constraints",
+			},
+		},
+		{
+			name: "globs",
+			l:    Locer{GOOS: "linux", Include: []string{"*.go"}, Exclude: []string{"legacy"}},
+			want: map[string]string{
+				"main.go":       "",
+				"legacy/old.go": "excluded by legacy",
+			},
+		},
+		{
+			name: "include",
+			l:    Locer{GOOS: "linux", Include: []string{"legacy/**"}},
+			want: map[string]string{
+				"main.go":       "not included",
+				"legacy/old.go": "",
+			},
+		},
+	}
+	for _, tc := range tests {
+		for name, want := range tc.want {
+			if got := tc.l.skipReason(filepath.FromSlash(name)); got != want {
+				t.Errorf("%s: skipReason(%s) = %q, want %q", tc.name, name, got, want)
+			}
+		}
+	}
+}
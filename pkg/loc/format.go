@@ -0,0 +1,178 @@
+package loc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// formatRex matches typed placeholders, such as {1,number}, {2,currency,EUR} or {when,date,long}.
+var formatRex = regexp.MustCompile(`\{(\w+)\s*,\s*(number|percent|currency|date|time)\s*(?:,\s*([^{}]*?)\s*)?\}`)
+
+// formatArgs renders the typed placeholders of text for lang, using the values in dataMap. Placeholders whose value
+// is missing or cannot be parsed are left for the plain {key} replacement.
+func formatArgs(lang string, text string, dataMap map[string]string) string {
+	if !strings.Contains(text, ",") {
+		return text // fast path; no typed placeholders.
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		tag = language.Und
+	}
+	var p *message.Printer
+	return formatRex.ReplaceAllStringFunc(text, func(m string) string {
+		groups := formatRex.FindStringSubmatch(m)
+		name, typ, style := groups[1], groups[2], groups[3]
+		raw, ok := dataMap[name]
+		if !ok {
+			return m
+		}
+		if p == nil {
+			p = message.NewPrinter(tag)
+		}
+
+		switch typ {
+		case "date", "time":
+			t, err := toTime(raw)
+			if err != nil {
+				Logger.Debug().Err(err).Msgf("cannot format '%s' as a %s", name, typ)
+				return "{" + name + "}"
+			}
+			return formatTime(tag, typ, style, t)
+		}
+
+		f, err := toFloat(raw)
+		if err != nil {
+			Logger.Debug().Err(err).Msgf("cannot format '%s' as a %s", name, typ)
+			return "{" + name + "}"
+		}
+		switch typ {
+		case "percent":
+			return p.Sprint(number.Percent(f))
+		case "currency":
+			unit, err := currencyUnit(tag, style)
+			if err != nil {
+				Logger.Debug().Err(err).Msgf("cannot format '%s' as a currency", name)
+				return p.Sprint(number.Decimal(f))
+			}
+			return formatCurrency(p, tag, unit, f)
+		default:
+			return formatNumber(p, style, f)
+		}
+	})
+}
+
+// currencyUnit returns the currency named by code, or the currency of tag's region if code is empty.
+func currencyUnit(tag language.Tag, code string) (currency.Unit, error) {
+	if code != "" {
+		return currency.ParseISO(code)
+	}
+	unit, conf := currency.FromTag(tag)
+	if conf == language.No {
+		return unit, fmt.Errorf("no currency known for %s", tag)
+	}
+	return unit, nil
+}
+
+// currencyAfter are the languages placing the currency symbol after the amount, as in CLDR's "#,##0.00 ¤", and
+// currencyTight those placing it before without a space, as in "¤#,##0.00"; others use "¤ #,##0.00".
+var (
+	currencyAfter = map[string]bool{"bg": true, "cs": true, "da": true, "de": true, "el": true, "es": true,
+		"fi": true, "fr": true, "hr": true, "hu": true, "it": true, "lt": true, "lv": true, "nb": true, "no": true,
+		"pl": true, "ro": true, "ru": true, "sk": true, "sl": true, "sr": true, "sv": true, "uk": true, "vi": true}
+	currencyTight = map[string]bool{"en": true, "he": true, "hi": true, "id": true, "ja": true, "ko": true,
+		"ms": true, "th": true, "tr": true, "zh": true}
+)
+
+// formatCurrency formats f in unit with the digits of the currency, placing its symbol as tag's language does.
+func formatCurrency(p *message.Printer, tag language.Tag, unit currency.Unit, f float64) string {
+	scale, _ := currency.Standard.Rounding(unit)
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	amount := p.Sprint(number.Decimal(f, number.Scale(scale)))
+	symbol := p.Sprint(currency.Symbol(unit))
+	base, _ := tag.Base()
+	switch {
+	case currencyAfter[base.String()]:
+		return sign + amount + "\u00a0" + symbol
+	case currencyTight[base.String()]:
+		return sign + symbol + amount
+	}
+	return sign + symbol + "\u00a0" + amount
+}
+
+// replacePlaceholders replaces every {key} in text with its value from dataMap.
+func replacePlaceholders(text string, dataMap map[string]string) string {
+	if len(dataMap) == 0 {
+		return text
+	}
+	var replData []string
+	for k, v := range dataMap {
+		replData = append(replData, "{"+k+"}", v)
+	}
+	return strings.NewReplacer(replData...).Replace(text)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case uint:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case string:
+		return strconv.ParseFloat(x, 64)
+	default:
+		return 0, fmt.Errorf("cannot use %T as a number", v)
+	}
+}
+
+func toTime(v interface{}) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case int64:
+		return time.Unix(x, 0), nil
+	case int:
+		return time.Unix(int64(x), 0), nil
+	case string:
+		if t, err := time.Parse(time.RFC3339, x); err == nil {
+			return t, nil
+		}
+		if secs, err := strconv.ParseInt(x, 10, 64); err == nil {
+			return time.Unix(secs, 0), nil
+		}
+		return time.Time{}, fmt.Errorf("cannot parse '%s' as a time; use RFC 3339 or unix seconds", x)
+	default:
+		return time.Time{}, fmt.Errorf("cannot use %T as a time", v)
+	}
+}
+
+func formatNumber(p *message.Printer, style string, f float64) string {
+	switch style {
+	case "integer":
+		return p.Sprint(number.Decimal(f, number.MaxFractionDigits(0)))
+	case "percent":
+		return p.Sprint(number.Percent(f))
+	default:
+		return p.Sprint(number.Decimal(f))
+	}
+}
@@ -0,0 +1,37 @@
+package loc
+
+import (
+	"testing"
+)
+
+func TestFormatArgs(t *testing.T) {
+	dataMap := map[string]string{"1": "1234567", "2": "0.25", "3": "1234.5", "4": "2024-03-05T14:30:00Z", "5": "abc"}
+	tests := []struct {
+		lang string
+		text string
+		want string
+	}{
+		{lang: "en-US", text: "{1,number} users", want: "1,234,567 users"},
+		{lang: "de-DE", text: "{1,number} Nutzer", want: "1.234.567 Nutzer"},
+		{lang: "hi-IN", text: "{1, number}", want: "12,34,567"},
+		{lang: "en-US", text: "{2,percent}", want: "25%"},
+		{lang: "de-DE", text: "{3,currency}", want: "1.234,50\u00a0€"},
+		{lang: "en-US", text: "{3,currency,JPY}", want: "¥1,234"}, // half to even, as ICU
+		{lang: "nl-NL", text: "{3,currency}", want: "€\u00a01.234,50"},
+		{lang: "en-US", text: "{4,date,short} at {4,time,short}", want: "3/5/24 at 2:30 PM"},
+		{lang: "en-GB", text: "{4,date,long} at {4,time,short}", want: "5 March 2024 at 14:30"},
+		{lang: "de-DE", text: "{4,date,full} um {4,time,short}", want: "Dienstag, 5. März 2024 um 14:30"},
+		{lang: "fr-FR", text: "{4,date,medium}", want: "5 mars 2024"},
+		{lang: "es-ES", text: "{4,date,long}", want: "5 de marzo de 2024"},
+		{lang: "ja-JP", text: "{4,date,long} {4,time}", want: "2024年3月5日 14:30:00"},
+		{lang: "sw-KE", text: "{4,date,long}", want: "2024-03-05"},
+		{lang: "en-US", text: "{5,number} and {1}", want: "abc and 1234567"},
+		{lang: "en-US", text: "{missing,number}", want: "{missing,number}"},
+	}
+	for _, tc := range tests {
+		got := replacePlaceholders(formatArgs(tc.lang, tc.text, dataMap), dataMap)
+		if got != tc.want {
+			t.Errorf("%s %q = %q, want %q", tc.lang, tc.text, got, tc.want)
+		}
+	}
+}
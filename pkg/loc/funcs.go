@@ -0,0 +1,81 @@
+package loc
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// qualified reports whether a configured func is a fully qualified name, such as fmt.Printf or
+// (*github.com/foo/tgbot.Bot).Send, rather than a bare name matching any call of that name.
+func qualified(name string) bool {
+	return strings.Contains(name, ".")
+}
+
+// normFunc drops the receiver parentheses and pointer of a qualified name, so (*pkg.T).M, (pkg.T).M and pkg.T.M
+// are all the same.
+func normFunc(name string) string {
+	return strings.NewReplacer("(", "", ")", "", "*", "").Replace(name)
+}
+
+// needsTypes reports whether any configured func is qualified, so calls must be matched using type information.
+func (l *Locer) needsTypes() bool {
+	for _, set := range []map[string]struct{}{l.Funcs, l.Fmtfuncs, l.Pluralfuncs} {
+		for name := range set {
+			if qualified(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inFuncs reports whether name, bare or qualified, is in set.
+func inFuncs(set map[string]struct{}, name string) bool {
+	if _, ok := set[name]; ok {
+		return true
+	}
+	for entry := range set {
+		if qualified(entry) && normFunc(entry) == normFunc(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// calleeIdent returns the identifier naming the function called by call, if it is called by name.
+func calleeIdent(call *ast.CallExpr) *ast.Ident {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	}
+	return nil
+}
+
+// matchFunc returns the entry of set which call is to. Bare names match any call of that name; qualified names
+// only match calls resolving to that function or method, which needs the type information of the current file.
+func (l *Locer) matchFunc(set map[string]struct{}, call *ast.CallExpr) (string, bool) {
+	id := calleeIdent(call)
+	if id == nil {
+		return "", false
+	}
+	if _, ok := set[id.Name]; ok {
+		return id.Name, true
+	}
+	if l.info == nil {
+		return "", false
+	}
+	fn, ok := l.info.Uses[id].(*types.Func)
+	if !ok {
+		return "", false
+	}
+	full := normFunc(fn.Origin().FullName())
+	for entry := range set {
+		if qualified(entry) && normFunc(entry) == full {
+			return entry, true
+		}
+	}
+	return "", false
+}
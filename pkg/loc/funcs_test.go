@@ -0,0 +1,75 @@
+package loc
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandlePackagesQualified(t *testing.T) {
+	dir := t.TempDir()
+	src := `package bot
+
+type Bot struct{}
+
+func (b *Bot) Send(text string) {}
+
+type Logger struct{}
+
+func (Logger) Send(text string) {}
+
+func getLang(u string) string { return u }
+
+func reply(b *Bot, log Logger, u string) {
+	b.Send("hello there")
+	log.Send("debug only")
+}
+`
+	for name, data := range map[string]string{
+		"go.mod": "module example.com/bot\n\ngo 1.22\n",
+		"bot.go": src,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	l := &Locer{
+		DefaultLang: "en-GB",
+		Fmtfuncs:    map[string]struct{}{"(*example.com/bot.Bot).Send": {}},
+		Checked:     make(map[string]struct{}),
+		Fset:        token.NewFileSet(),
+		Apply:       true,
+		TransDir:    filepath.Join(dir, "trans"),
+	}
+	if err := l.Handle([]string{"."}, l.Fix); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "bot.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, ok := range map[string]bool{
+		`b.Send(goloc.Trnl(lang, "bot.go:1"))`: true,
+		`log.Send("debug only")`:               true,
+		`b.Send("hello there")`:                false,
+	} {
+		if strings.Contains(string(got), want) != ok {
+			t.Errorf("contains %s = %v, want %v:\n%s", want, !ok, ok, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "trans", "en-GB", "bot.xml")); err != nil {
+		t.Error(err)
+	}
+}
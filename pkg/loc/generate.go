@@ -0,0 +1,88 @@
+package loc
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Generate compiles every translation file into Go source for package pkg. Importing the generated package adds all
+// translations to the default catalog, so binaries built with it need no translation files at runtime. Any file
+// which fails to decode aborts generation.
+func (l *Locer) Generate(w io.Writer, pkg string) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name '%s'", pkg)
+	}
+	fsys := os.DirFS(l.transDir())
+	langs, err := langDirs(fsys)
+	if err != nil {
+		return err
+	}
+	if len(langs) == 0 {
+		return fmt.Errorf("no translations found in %s", l.transDir())
+	}
+	ff := l.fileFormat()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by goloc generate from %s; DO NOT EDIT.\n\n", l.transDir())
+	fmt.Fprintf(&buf, "package %s\n\nimport %q\n\nfunc init() {\n", pkg, runtimeImport)
+	for _, lang := range langs {
+		err := walkModules(fsys, lang, func(file string) error {
+			// registered under the same module name as Load uses, so reloaded files replace the compiled in data.
+			moduleName := moduleOf(file)
+			t, err := readModule(fsys, ff, lang, moduleName)
+			if err != nil {
+				return err
+			}
+			writeModuleLit(&buf, lang, moduleName, t)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// writeModuleLit writes the goloc.AddModule call registering t. Comments and empty rows aren't needed at runtime,
+// so they are left out.
+func writeModuleLit(buf *bytes.Buffer, lang string, moduleName string, t Translation) {
+	q := strconv.Quote
+	fmt.Fprintf(buf, "goloc.AddModule(%s, %s, goloc.Translation{\nCounter: %d,\nRows: []goloc.Value{\n", q(lang), q(moduleName), t.Counter)
+	for _, v := range t.Rows {
+		if v.Name == "" {
+			continue
+		}
+		fmt.Fprintf(buf, "{Id: %d, Name: %s", v.Id, q(v.Name))
+		if v.Value != "" {
+			fmt.Fprintf(buf, ", Value: %s", q(v.Value))
+		}
+		if len(v.Plurals) > 0 {
+			buf.WriteString(", Plurals: []goloc.Plural{")
+			for _, p := range v.Plurals {
+				fmt.Fprintf(buf, "{Form: %s, Value: %s}, ", q(p.Form), q(p.Value))
+			}
+			buf.WriteString("}")
+		}
+		if v.Select != nil {
+			fmt.Fprintf(buf, ", Select: &goloc.Select{Arg: %s, Cases: []goloc.Case{", q(v.Select.Arg))
+			for _, c := range v.Select.Cases {
+				fmt.Fprintf(buf, "{Key: %s, Value: %s}, ", q(c.Key), q(c.Value))
+			}
+			buf.WriteString("}}")
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("},\n})\n")
+}
@@ -0,0 +1,116 @@
+package loc
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// typeCheck type-checks the generated file src. This package stands in for the goloc runtime it imports as goloc.
+func typeCheck(t *testing.T, name string, src string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	for _, imp := range file.Imports {
+		if imp.Path.Value == strconv.Quote(runtimeImport) && imp.Name == nil {
+			imp.Name = &ast.Ident{Name: "goloc"}
+		}
+	}
+	conf := types.Config{Importer: runtimeImporter{}}
+	if _, err := conf.Check("gen", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, src)
+	}
+}
+
+// sourceImporter is shared by the tests, as importing this package from source is slow.
+var sourceImporter = sync.OnceValue(func() types.ImporterFrom {
+	return importer.ForCompiler(token.NewFileSet(), "source", nil).(types.ImporterFrom)
+})
+
+type runtimeImporter struct{}
+
+func (runtimeImporter) Import(path string) (*types.Package, error) {
+	if path == runtimeImport {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return sourceImporter().ImportFrom(".", wd, 0)
+	}
+	return sourceImporter().Import(path)
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, testTree())
+	// modules in a format other than the preferred one are compiled in too.
+	admin := Translation{Counter: 1, Rows: []Value{{Id: 1, Name: "bot/admin.go:1", Value: "gesperrt"}}}
+	if err := writeModuleFile(JSONFormat, filepath.Join(dir, "de-DE", "bot", "admin.json"), admin); err != nil {
+		t.Fatal(err)
+	}
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+
+	var sb strings.Builder
+	if err := l.Generate(&sb, "trans"); err != nil {
+		t.Fatal(err)
+	}
+	src := sb.String()
+	typeCheck(t, "goloc_gen.go", src)
+	for _, want := range []string{
+		"// Code generated by goloc generate",
+		`goloc.AddModule("de-DE", "bot/main.go", goloc.Translation{`,
+		`goloc.AddModule("de-DE", "bot/admin.go", goloc.Translation{`,
+		`{Id: 1, Name: "bot/admin.go:1", Value: "gesperrt"},`,
+		`{Id: 1, Name: "bot/main.go:1", Value: "hallo {1}"},`,
+		`Plurals: []goloc.Plural{{Form: "one", Value: "{1} file"}, {Form: "other", Value: "{1} files"}}`,
+		`Select: &goloc.Select{Arg: "g", Cases: []goloc.Case{{Key: "male", Value: "he"}, {Key: "other", Value: "they"}}}`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code is missing %q:\n%s", want, src)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "de-DE", "broken.xml"), []byte("<translation><Rows>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Generate(&sb, "trans"); err == nil {
+		t.Error("Generate() should fail on a malformed file")
+	}
+}
+
+func TestAddModuleSurvivesReload(t *testing.T) {
+	c := NewCatalogFS("en-US", os.DirFS(t.TempDir()))
+	c.AddModule("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "hello"}}})
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Trnl("en-US", "mod.go:1"); got != "hello" {
+		t.Errorf("Trnl() = %q, want %q", got, "hello")
+	}
+}
+
+func TestAddModuleOverriddenByFiles(t *testing.T) {
+	fsys := fstest.MapFS{}
+	c := NewCatalogFS("en-US", fsys)
+	c.AddModule("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "compiled"}}})
+	fsys["en-US/mod.json"] = &fstest.MapFile{Data: []byte(`{"rows": [{"id": 1, "name": "mod.go:1", "value": "on disk"}]}`)}
+	c.Load("mod.go")
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Trnl("en-US", "mod.go:1"); got != "on disk" {
+		t.Errorf("Trnl() = %q, want the file on disk to override the compiled in %q", got, "on disk")
+	}
+}
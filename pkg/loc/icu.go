@@ -0,0 +1,539 @@
+package loc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// icuNode is a single part of a parsed ICU MessageFormat message; one of icuText, icuPound or *icuArg.
+type icuNode interface{}
+
+type icuText string
+
+// icuPound is the # inside a plural case, which renders the plural argument (minus any offset).
+type icuPound struct{}
+
+type icuArg struct {
+	name   string
+	typ    string // "", number, date, time, plural, selectordinal, select
+	style  string
+	offset float64
+	cases  []icuCase
+}
+
+type icuCase struct {
+	key string // "=N", a plural category, or a select value
+	msg []icuNode
+}
+
+type icuMessage []icuNode
+
+// parseICU parses an ICU MessageFormat message, such as "{count, plural, one {# file} other {# files}}".
+func parseICU(s string) (icuMessage, error) {
+	p := &icuParser{s: []rune(s)}
+	nodes, err := p.message(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected '%c'", p.s[p.pos])
+	}
+	return nodes, nil
+}
+
+type icuParser struct {
+	s   []rune
+	pos int
+}
+
+func (p *icuParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("icu syntax error at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *icuParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(p.s[p.pos]) {
+		p.pos++
+	}
+}
+
+// message parses text and arguments until the end of input, or an unmatched '}' when nested.
+func (p *icuParser) message(inPlural bool) ([]icuNode, error) {
+	var nodes []icuNode
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, icuText(text.String()))
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.s) {
+		r := p.s[p.pos]
+		switch {
+		case r == '\'':
+			p.pos++
+			switch {
+			case p.pos < len(p.s) && p.s[p.pos] == '\'':
+				text.WriteRune('\'')
+				p.pos++
+			case p.pos < len(p.s) && (p.s[p.pos] == '{' || p.s[p.pos] == '}' || (inPlural && p.s[p.pos] == '#')):
+				// quoted literal, up to the next single apostrophe.
+				for p.pos < len(p.s) {
+					if p.s[p.pos] == '\'' {
+						if p.pos+1 < len(p.s) && p.s[p.pos+1] == '\'' {
+							text.WriteRune('\'')
+							p.pos += 2
+							continue
+						}
+						break
+					}
+					text.WriteRune(p.s[p.pos])
+					p.pos++
+				}
+				if p.pos >= len(p.s) {
+					return nil, p.errorf("unterminated quote")
+				}
+				p.pos++
+			default:
+				text.WriteRune('\'')
+			}
+		case r == '#' && inPlural:
+			flush()
+			nodes = append(nodes, icuPound{})
+			p.pos++
+		case r == '{':
+			flush()
+			p.pos++
+			arg, err := p.argument(inPlural)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, arg)
+		case r == '}':
+			flush()
+			return nodes, nil
+		default:
+			text.WriteRune(r)
+			p.pos++
+		}
+	}
+	flush()
+	return nodes, nil
+}
+
+func (p *icuParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		r := p.s[p.pos]
+		if unicode.IsSpace(r) || r == ',' || r == '{' || r == '}' || r == '\'' || r == '#' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.s[start:p.pos])
+}
+
+func (p *icuParser) expect(r rune) error {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return p.errorf("expected '%c', got end of message", r)
+	}
+	if p.s[p.pos] != r {
+		return p.errorf("expected '%c', got '%c'", r, p.s[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+// argument parses the inside of {...}, after the opening brace.
+func (p *icuParser) argument(inPlural bool) (*icuArg, error) {
+	p.skipSpace()
+	arg := &icuArg{name: p.ident()}
+	if arg.name == "" {
+		return nil, p.errorf("missing argument name")
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return arg, nil
+	}
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	arg.typ = p.ident()
+
+	switch arg.typ {
+	case "number", "date", "time":
+		p.skipSpace()
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+			start := p.pos
+			for p.pos < len(p.s) && p.s[p.pos] != '}' {
+				p.pos++
+			}
+			arg.style = strings.TrimSpace(string(p.s[start:p.pos]))
+		}
+		return arg, p.expect('}')
+
+	case "plural", "selectordinal", "select":
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+		if err := p.cases(arg, inPlural); err != nil {
+			return nil, err
+		}
+		return arg, p.expect('}')
+
+	case "":
+		return nil, p.errorf("missing type for argument '%s'", arg.name)
+	default:
+		return nil, p.errorf("unknown type '%s' for argument '%s'", arg.typ, arg.name)
+	}
+}
+
+// cases parses the cases of a plural or select; a # in a select nested inside a plural still refers to the plural.
+func (p *icuParser) cases(arg *icuArg, inPlural bool) error {
+	isPlural := arg.typ != "select"
+	seen := make(map[string]struct{})
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) || p.s[p.pos] == '}' {
+			break
+		}
+		key := p.ident()
+		if isPlural && strings.HasPrefix(key, "offset:") {
+			if len(arg.cases) > 0 {
+				return p.errorf("offset must come before any case")
+			}
+			off, err := strconv.ParseFloat(strings.TrimPrefix(key, "offset:"), 64)
+			if err != nil {
+				return p.errorf("invalid offset '%s'", key)
+			}
+			arg.offset = off
+			continue
+		}
+		if key == "" {
+			return p.errorf("missing case key in '%s'", arg.name)
+		}
+		if isPlural && !strings.HasPrefix(key, "=") && !isPluralCategory(key) {
+			return p.errorf("invalid plural case '%s' in '%s'", key, arg.name)
+		}
+		if _, ok := seen[key]; ok {
+			return p.errorf("duplicate case '%s' in '%s'", key, arg.name)
+		}
+		seen[key] = struct{}{}
+		if err := p.expect('{'); err != nil {
+			return err
+		}
+		msg, err := p.message(isPlural || inPlural)
+		if err != nil {
+			return err
+		}
+		if err := p.expect('}'); err != nil {
+			return err
+		}
+		arg.cases = append(arg.cases, icuCase{key: key, msg: msg})
+	}
+	if _, ok := seen["other"]; !ok {
+		return p.errorf("'%s' has no 'other' case", arg.name)
+	}
+	return nil
+}
+
+func isPluralCategory(s string) bool {
+	for _, p := range pluralFormNames {
+		if p.name == s {
+			return true
+		}
+	}
+	return false
+}
+
+// args returns the names of every argument used by the message, mapped to their type.
+func (m icuMessage) args() map[string]string {
+	out := make(map[string]string)
+	var walk func(nodes []icuNode)
+	walk = func(nodes []icuNode) {
+		for _, n := range nodes {
+			arg, ok := n.(*icuArg)
+			if !ok {
+				continue
+			}
+			out[arg.name] = arg.typ
+			for _, c := range arg.cases {
+				walk(c.msg)
+			}
+		}
+	}
+	walk(m)
+	return out
+}
+
+// render formats the message for lang, taking argument values from args.
+func (m icuMessage) render(lang string, args map[string]interface{}) (string, error) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		tag = language.Und
+	}
+	r := &icuRenderer{lang: lang, tag: tag, printer: message.NewPrinter(tag), args: args}
+	var sb strings.Builder
+	if err := r.render(&sb, m, nil); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+type icuRenderer struct {
+	lang    string
+	tag     language.Tag
+	printer *message.Printer
+	args    map[string]interface{}
+}
+
+func (r *icuRenderer) render(sb *strings.Builder, nodes []icuNode, pound *float64) error {
+	for _, n := range nodes {
+		switch x := n.(type) {
+		case icuText:
+			sb.WriteString(string(x))
+		case icuPound:
+			if pound == nil {
+				sb.WriteRune('#')
+				continue
+			}
+			sb.WriteString(r.printer.Sprint(number.Decimal(*pound)))
+		case *icuArg:
+			if err := r.renderArg(sb, x, pound); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *icuRenderer) renderArg(sb *strings.Builder, arg *icuArg, pound *float64) error {
+	v, ok := r.args[arg.name]
+	if !ok {
+		return fmt.Errorf("missing value for argument '%s'", arg.name)
+	}
+
+	switch arg.typ {
+	case "":
+		sb.WriteString(fmt.Sprint(v))
+		return nil
+
+	case "number":
+		f, err := toFloat(v)
+		if err != nil {
+			return fmt.Errorf("argument '%s': %w", arg.name, err)
+		}
+		sb.WriteString(formatNumber(r.printer, arg.style, f))
+		return nil
+
+	case "date", "time":
+		t, err := toTime(v)
+		if err != nil {
+			return fmt.Errorf("argument '%s': %w", arg.name, err)
+		}
+		sb.WriteString(formatTime(r.tag, arg.typ, arg.style, t))
+		return nil
+
+	case "select":
+		key := fmt.Sprint(v)
+		return r.render(sb, pickCase(arg.cases, func(k string) bool { return k == key }), pound)
+
+	default: // plural, selectordinal
+		f, err := toFloat(v)
+		if err != nil {
+			return fmt.Errorf("argument '%s': %w", arg.name, err)
+		}
+		rel := f - arg.offset
+		form := r.pluralForm(arg.typ == "selectordinal", rel)
+		exact := "=" + strconv.FormatFloat(f, 'f', -1, 64)
+		match := func(k string) bool { return k == form }
+		for _, c := range arg.cases {
+			if c.key == exact { // exact matches win over categories
+				match = func(k string) bool { return k == exact }
+				break
+			}
+		}
+		msg := pickCase(arg.cases, match)
+		return r.render(sb, msg, &rel)
+	}
+}
+
+func (r *icuRenderer) pluralForm(ordinal bool, f float64) string {
+	if f != math.Trunc(f) {
+		return "other"
+	}
+	n := int(f)
+	if ordinal {
+		return countForm(plural.Ordinal, r.tag, n)
+	}
+	return pluralForm(r.lang, n)
+}
+
+// pickCase returns the first case matching match, or the "other" case.
+func pickCase(cases []icuCase, match func(key string) bool) []icuNode {
+	var other []icuNode
+	for _, c := range cases {
+		if match(c.key) {
+			return c.msg
+		}
+		if c.key == "other" {
+			other = c.msg
+		}
+	}
+	return other
+}
+
+// checkICU parses custom as an ICU message, and makes sure it uses the same arguments as def.
+func checkICU(def string, custom string) error {
+	customMsg, err := parseICU(custom)
+	if err != nil {
+		return err
+	}
+	defMsg, err := parseICU(def)
+	if err != nil {
+		return fmt.Errorf("default language: %w", err)
+	}
+
+	defArgs := defMsg.args()
+	customArgs := customMsg.args()
+	var problems []string
+	for name, typ := range customArgs {
+		defTyp, ok := defArgs[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown argument '%s'", name))
+		case defTyp != typ:
+			problems = append(problems, fmt.Sprintf("argument '%s' is a %s, should be a %s", name, icuTypeName(typ), icuTypeName(defTyp)))
+		}
+	}
+	for name := range defArgs {
+		if _, ok := customArgs[name]; !ok {
+			problems = append(problems, fmt.Sprintf("missing argument '%s'", name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("argument mismatch: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func icuTypeName(typ string) string {
+	if typ == "" {
+		return "simple argument"
+	}
+	return typ
+}
+
+// icuArgs turns Trnlf arguments into ICU arguments.
+func icuArgs(dataMap map[string]string) map[string]interface{} {
+	args := make(map[string]interface{}, len(dataMap))
+	for k, v := range dataMap {
+		args[k] = v
+	}
+	return args
+}
+
+// icuCacheSize is the most parsed messages a catalog keeps.
+const icuCacheSize = 4096
+
+// icuCache caches parsed ICU messages by their text. Once full it is emptied, so texts which reloads dropped
+// don't stay around forever.
+type icuCache struct {
+	mu   sync.RWMutex
+	msgs map[string]icuMessage
+}
+
+func (ic *icuCache) get(text string) (icuMessage, bool) {
+	ic.mu.RLock()
+	defer ic.mu.RUnlock()
+	msg, ok := ic.msgs[text]
+	return msg, ok
+}
+
+func (ic *icuCache) put(text string, msg icuMessage) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if ic.msgs == nil || len(ic.msgs) >= icuCacheSize {
+		ic.msgs = make(map[string]icuMessage)
+	}
+	ic.msgs[text] = msg
+}
+
+// icuRender renders text as an ICU message for lang, using the catalog's cache of parsed messages.
+func (c *Catalog) icuRender(lang string, text string, args map[string]interface{}) (string, error) {
+	msg, ok := c.parsed.get(text)
+	if !ok {
+		var err error
+		if msg, err = parseICU(text); err != nil {
+			return "", err
+		}
+		c.parsed.put(text, msg)
+	}
+	return msg.render(lang, args)
+}
+
+// renderICU renders text for Trnlf and Trnpf, logging errors and returning the unrendered text if it fails.
+func (c *Catalog) renderICU(lang string, trnlVal string, text string, dataMap map[string]string) string {
+	if text == "" {
+		return ""
+	}
+	out, err := c.icuRender(lang, text, icuArgs(dataMap))
+	if err != nil {
+		Logger.Error().Err(err).Msgf("failed to render %s for %s", trnlVal, lang)
+		return text
+	}
+	return out
+}
+
+// SetICU turns ICU MessageFormat rendering of message values on or off for Trnlf, Trnpf and Trnlm.
+func (c *Catalog) SetICU(on bool) {
+	c.mu.Lock()
+	c.icu = on
+	c.mu.Unlock()
+}
+
+func (c *Catalog) icuEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.icu
+}
+
+// Trnlm renders trnlVal as an ICU MessageFormat message, with typed arguments such as numbers and time.Time.
+// Rendering errors are logged, and the unrendered message returned.
+func (c *Catalog) Trnlm(lang string, trnlVal string, args map[string]interface{}) string {
+	return c.resolve(lang, trnlVal, func(l string, v Value) string {
+		text := v.text(nil)
+		if text == "" {
+			return ""
+		}
+		out, err := c.icuRender(l, text, args)
+		if err != nil {
+			Logger.Error().Err(err).Msgf("failed to render %s for %s", trnlVal, l)
+			return text
+		}
+		return out
+	})
+}
+
+func SetICU(on bool) {
+	pkgCatalog().SetICU(on)
+}
+
+func Trnlm(lang string, trnlVal string, args map[string]interface{}) string {
+	return pkgCatalog().Trnlm(lang, trnlVal, args)
+}
@@ -0,0 +1,146 @@
+package loc
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICURender(t *testing.T) {
+	when := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		lang string
+		msg  string
+		args map[string]interface{}
+		want string
+	}{
+		{name: "simple", lang: "en", msg: "Hello {name}!", args: map[string]interface{}{"name": "Bob"}, want: "Hello Bob!"},
+		{name: "legacy", lang: "en", msg: "{1} and {2}", args: map[string]interface{}{"1": "a", "2": "b"}, want: "a and b"},
+		{name: "quotes", lang: "en", msg: "It''s '{literal}' isn't it", args: nil, want: "It's {literal} isn't it"},
+		{name: "number en", lang: "en", msg: "{n, number}", args: map[string]interface{}{"n": 1234567.5}, want: "1,234,567.5"},
+		{name: "number de", lang: "de", msg: "{n, number}", args: map[string]interface{}{"n": 1234567.5}, want: "1.234.567,5"},
+		{name: "percent", lang: "en", msg: "{n, number, percent}", args: map[string]interface{}{"n": 0.25}, want: "25%"},
+		{name: "date", lang: "en", msg: "{d, date, short} {d, time, short}", args: map[string]interface{}{"d": when}, want: "3/5/24 2:30 PM"},
+		{
+			name: "plural",
+			lang: "en",
+			msg:  "{n, plural, =0 {no files} one {# file} other {# files}}",
+			args: map[string]interface{}{"n": 1},
+			want: "1 file",
+		},
+		{
+			name: "plural exact",
+			lang: "en",
+			msg:  "{n, plural, =0 {no files} one {# file} other {# files}}",
+			args: map[string]interface{}{"n": "0"},
+			want: "no files",
+		},
+		{
+			name: "plural pl",
+			lang: "pl",
+			msg:  "{n, plural, one {# plik} few {# pliki} many {# plików} other {# pliku}}",
+			args: map[string]interface{}{"n": 22},
+			want: "22 pliki",
+		},
+		{
+			name: "offset",
+			lang: "en",
+			msg:  "{n, plural, offset:1 =1 {only {who}} one {{who} and # other} other {{who} and # others}}",
+			args: map[string]interface{}{"n": 3, "who": "Ann"},
+			want: "Ann and 2 others",
+		},
+		{
+			name: "nested select",
+			lang: "en",
+			msg:  "{g, select, female {{n, plural, one {She has # cat} other {She has # cats}}} other {{n, plural, one {They have # cat} other {They have # cats}}}}",
+			args: map[string]interface{}{"g": "female", "n": 2},
+			want: "She has 2 cats",
+		},
+		{name: "plural large", lang: "en", msg: "{n, plural, one {# file} other {# files}}", args: map[string]interface{}{"n": 20000001}, want: "20,000,001 files"},
+		{name: "plural large fr", lang: "fr", msg: "{n, plural, one {# fichier} other {# fichiers}}", args: map[string]interface{}{"n": 10000000}, want: "10\u00a0000\u00a0000 fichiers"},
+		{name: "ordinal", lang: "en", msg: "{n, selectordinal, one {#st} two {#nd} few {#rd} other {#th}}", args: map[string]interface{}{"n": 23}, want: "23rd"},
+	}
+	for _, tc := range tests {
+		msg, err := parseICU(tc.msg)
+		if err != nil {
+			t.Errorf("%s: parseICU() error = %v", tc.name, err)
+			continue
+		}
+		got, err := msg.render(tc.lang, tc.args)
+		if err != nil {
+			t.Errorf("%s: render() error = %v", tc.name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: render() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestICUSyntaxErrors(t *testing.T) {
+	for _, msg := range []string{
+		"{unclosed",
+		"too many }",
+		"{n, plural, one {x}}",
+		"{n, plural, lots {x} other {y}}",
+		"{n, bogus}",
+		"{n, select, a {x} a {y} other {z}}",
+		"'{unterminated",
+	} {
+		if _, err := parseICU(msg); err == nil {
+			t.Errorf("parseICU(%q) should fail", msg)
+		}
+	}
+}
+
+func TestCheckICU(t *testing.T) {
+	def := "{n, plural, one {# file by {who}} other {# files by {who}}}"
+	tests := []struct {
+		custom  string
+		wantErr string
+	}{
+		{custom: "{n, plural, one {# Datei von {who}} other {# Dateien von {who}}}"},
+		{custom: "{n, plural, one {# Datei} other {# Dateien}}", wantErr: "missing argument 'who'"},
+		{custom: "{n, plural, one {# Datei von {wer}} other {# Dateien von {wer}}}", wantErr: "unknown argument 'wer'"},
+		{custom: "{n} Dateien von {who}", wantErr: "argument 'n' is a simple argument, should be a plural"},
+		{custom: "{n, plural, one {# Datei}", wantErr: "syntax error"},
+	}
+	for _, tc := range tests {
+		err := checkICU(def, tc.custom)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Errorf("checkICU(%q) error = %v", tc.custom, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Errorf("checkICU(%q) error = %v, want %q", tc.custom, err, tc.wantErr)
+		}
+	}
+}
+
+func TestCatalogICU(t *testing.T) {
+	c := NewCatalog("en-US")
+	c.add("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "{1, plural, one {# file} other {# files}}"}}})
+	c.SetICU(true)
+	if got := c.Trnlf("en-US", "mod.go:1", map[string]string{"1": "3"}); got != "3 files" {
+		t.Errorf("Trnlf() = %q, want %q", got, "3 files")
+	}
+	if got := c.Trnlm("de", "mod.go:1", map[string]interface{}{"1": 1}); got != "1 file" {
+		t.Errorf("Trnlm() = %q, want %q", got, "1 file")
+	}
+}
+
+func TestICUCacheBounded(t *testing.T) {
+	c := NewCatalog("en-US")
+	for i := 0; i <= icuCacheSize; i++ {
+		if _, err := c.icuRender("en-US", "{n} #"+strconv.Itoa(i), map[string]interface{}{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(c.parsed.msgs); n > icuCacheSize {
+		t.Errorf("%d messages cached, want at most %d", n, icuCacheSize)
+	}
+	if _, ok := c.parsed.get("{n} #" + strconv.Itoa(icuCacheSize)); !ok {
+		t.Error("latest message not cached")
+	}
+}
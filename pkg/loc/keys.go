@@ -0,0 +1,253 @@
+package loc
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// argRex matches the {1} and {name} placeholders of a value, including typed ones such as {1,number}.
+var argRex = regexp.MustCompile(`\{(\w+)\s*(?:,[^{}]*)?\}`)
+
+// keyArg is a placeholder of a value, as a parameter of its generated accessor.
+type keyArg struct {
+	name  string // placeholder name, eg 1
+	param string // parameter name, eg p1
+}
+
+// GenerateKeys writes Go source for package pkg with a constant and a typed accessor function for every key of the
+// default language. Accessors take one string parameter per placeholder, plus the count of plural values, so a
+// removed or changed message breaks the build at every call site instead of silently translating to "".
+func (l *Locer) GenerateKeys(w io.Writer, pkg string) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name '%s'", pkg)
+	}
+	sets, _, err := l.loadModuleSets()
+	if err != nil {
+		return err
+	}
+	var keys []Value
+	var modules []string
+	for _, set := range sets {
+		for _, v := range set.def.Rows {
+			if v.Name != "" {
+				keys = append(keys, v)
+				modules = append(modules, sourceModule(v.Name))
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys found for default language %s in %s", l.DefaultLang, l.transDir())
+	}
+	prefix := commonDir(modules)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by goloc keys from %s; DO NOT EDIT.\n\n", l.transDir())
+	fmt.Fprintf(&buf, "package %s\n\nimport %q\n", pkg, runtimeImport)
+
+	seen := make(map[string]string)
+	for _, v := range keys {
+		ident := keyIdent(strings.TrimPrefix(v.Name, prefix))
+		// each key declares both ident and identKey, which mustn't clash with either of another key's.
+		for _, name := range []string{ident, ident + "Key"} {
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("keys '%s' and '%s' both generate %s", other, v.Name, name)
+			}
+		}
+		seen[ident] = v.Name
+		seen[ident+"Key"] = v.Name
+
+		args, err := l.keyArgs(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", v.Name, err)
+		}
+		fmt.Fprintf(&buf, "\n// %sKey is the key of %s.\nconst %sKey = %q\n", ident, strconv.Quote(keyText(v)), ident, v.Name)
+		writeAccessor(&buf, ident, v, args)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func writeAccessor(buf *bytes.Buffer, ident string, v Value, args []keyArg) {
+	params := []string{"lang string"}
+	plural := len(v.Plurals) > 0
+	if plural {
+		params = append(params, "n int")
+	}
+	for _, a := range args {
+		params = append(params, a.param+" string")
+	}
+	fmt.Fprintf(buf, "\n// %s translates %sKey into lang.\nfunc %s(%s) string {\n", ident, ident, ident, strings.Join(params, ", "))
+
+	var dataMap strings.Builder
+	dataMap.WriteString("map[string]string{")
+	for _, a := range args {
+		fmt.Fprintf(&dataMap, "%q: %s, ", a.name, a.param)
+	}
+	dataMap.WriteString("}")
+
+	switch {
+	case plural:
+		fmt.Fprintf(buf, "return goloc.Trnpf(lang, %sKey, n, %s)\n}\n", ident, dataMap.String())
+	case len(args) > 0:
+		fmt.Fprintf(buf, "return goloc.Trnlf(lang, %sKey, %s)\n}\n", ident, dataMap.String())
+	default:
+		fmt.Fprintf(buf, "return goloc.Trnl(lang, %sKey)\n}\n", ident)
+	}
+}
+
+// keyArgs returns the placeholders of v, numbered ones first, followed by named ones and any select argument in
+// the order they first appear.
+func (l *Locer) keyArgs(v Value) ([]keyArg, error) {
+	texts := []string{v.Value}
+	for _, p := range v.Plurals {
+		texts = append(texts, p.Value)
+	}
+	var names []string
+	if v.Select != nil {
+		names = append(names, v.Select.Arg)
+		for _, c := range v.Select.Cases {
+			texts = append(texts, c.Value)
+		}
+	}
+
+	for _, text := range texts {
+		if l.ICU {
+			msg, err := parseICU(text)
+			if err != nil {
+				return nil, err
+			}
+			var icuNames []string
+			for name, typ := range msg.args() {
+				if len(v.Plurals) > 0 && typ == "plural" {
+					continue // the count is passed as n
+				}
+				icuNames = append(icuNames, name)
+			}
+			sort.Strings(icuNames)
+			names = append(names, icuNames...)
+			continue
+		}
+		for _, m := range argRex.FindAllStringSubmatch(text, -1) {
+			names = append(names, m[1])
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var numbered, named []string
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		if _, err := strconv.Atoi(name); err == nil {
+			numbered = append(numbered, name)
+		} else {
+			named = append(named, name)
+		}
+	}
+	sort.Slice(numbered, func(i, j int) bool {
+		a, _ := strconv.Atoi(numbered[i])
+		b, _ := strconv.Atoi(numbered[j])
+		return a < b
+	})
+
+	var out []keyArg
+	for _, name := range append(numbered, named...) {
+		out = append(out, keyArg{name: name, param: paramName(name)})
+	}
+	return out, nil
+}
+
+// paramName returns a Go parameter name for a placeholder which can't clash with the fixed lang and n parameters.
+func paramName(name string) string {
+	if _, err := strconv.Atoi(name); err == nil {
+		return "p" + name
+	}
+	param := lowerFirst(keyIdent(name))
+	if param == "lang" || param == "n" || token.IsKeyword(param) || param == "" {
+		return param + "Arg"
+	}
+	return param
+}
+
+// keyIdent turns a key such as bot/main.go:12 into an exported identifier such as BotMain12.
+func keyIdent(key string) string {
+	module, id := key, ""
+	if i := strings.LastIndex(key, ":"); i >= 0 {
+		module, id = key[:i], key[i+1:]
+	}
+	module = strings.TrimSuffix(module, path.Ext(module))
+
+	var sb strings.Builder
+	upper := true
+	for _, r := range module {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	ident := sb.String()
+	if ident != "" && unicode.IsDigit([]rune(ident)[len([]rune(ident))-1]) {
+		// keep v2:17 apart from v21:7
+		ident += "_"
+	}
+	ident += id
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		ident = "Key" + ident
+	}
+	return ident
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// commonDir returns the longest directory prefix, ending in a slash, shared by all modules.
+func commonDir(modules []string) string {
+	prefix := modules[0]
+	for _, m := range modules[1:] {
+		for !strings.HasPrefix(m, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		return prefix[:i+1]
+	}
+	return ""
+}
+
+// keyText returns the default text of v, for documentation.
+func keyText(v Value) string {
+	switch {
+	case len(v.Plurals) > 0:
+		return v.Plural("other")
+	case v.Select != nil:
+		return v.Select.Case("other")
+	default:
+		return v.Value
+	}
+}
@@ -0,0 +1,68 @@
+package loc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateKeys(t *testing.T) {
+	dir := t.TempDir()
+	tree := testTree()
+	tree["en-GB/bot/admin.xml"] = Translation{Counter: 1, Rows: []Value{
+		{Id: 1, Name: "bot/admin.go:1", Value: "{user} banned {1} for {2,number} days"},
+		{Id: 2, Name: "bot/admin.go:2", Value: "done"},
+	}}
+	writeTestTree(t, dir, tree)
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+
+	var sb strings.Builder
+	if err := l.GenerateKeys(&sb, "msg"); err != nil {
+		t.Fatal(err)
+	}
+	src := sb.String()
+	typeCheck(t, "keys_gen.go", src)
+	for _, want := range []string{
+		`const Admin1Key = "bot/admin.go:1"`,
+		`func Admin1(lang string, p1 string, p2 string, user string) string {`,
+		`return goloc.Trnlf(lang, Admin1Key, map[string]string{"1": p1, "2": p2, "user": user})`,
+		`func Admin2(lang string) string {`,
+		`return goloc.Trnl(lang, Admin2Key)`,
+		`func Main2(lang string, n int, p1 string) string {`,
+		`return goloc.Trnpf(lang, Main2Key, n, map[string]string{"1": p1})`,
+		`func Main3(lang string, g string) string {`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated code is missing %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateKeysClash(t *testing.T) {
+	for _, rows := range [][]Value{
+		{{Id: 1, Name: "Foo", Value: "a"}, {Id: 2, Name: "FooKey", Value: "b"}},
+		{{Id: 1, Name: "FooKey", Value: "b"}, {Id: 2, Name: "Foo", Value: "a"}},
+	} {
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]Translation{"en-GB/foo.xml": {Counter: 2, Rows: rows}})
+		l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+		var sb strings.Builder
+		err := l.GenerateKeys(&sb, "msg")
+		if err == nil || !strings.Contains(err.Error(), "both generate FooKey") {
+			t.Errorf("keys %s then %s: error = %v, want a FooKey clash", rows[0].Name, rows[1].Name, err)
+		}
+	}
+}
+
+func TestKeyIdent(t *testing.T) {
+	tests := map[string]string{
+		"bot/main.go:12":    "BotMain12",
+		"main.go:1":         "Main1",
+		"1st-run.go:3":      "Key1stRun3",
+		"handlers_v2.go:17": "HandlersV2_17",
+	}
+	for key, want := range tests {
+		if got := keyIdent(key); got != want {
+			t.Errorf("keyIdent(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package loc
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultLangExprs are the lang templates used if LangExprs is empty.
+var DefaultLangExprs = []string{"getLang(u)"}
+
+// ValidateLangExprs returns an error if any of exprs is not a Go expression.
+func ValidateLangExprs(exprs []string) error {
+	for _, expr := range exprs {
+		if _, err := parser.ParseExpr(expr); err != nil {
+			return fmt.Errorf("invalid lang expression '%s': %w", expr, err)
+		}
+	}
+	return nil
+}
+
+func (l *Locer) langExprs() []string {
+	if len(l.LangExprs) == 0 {
+		return DefaultLangExprs
+	}
+	return l.LangExprs
+}
+
+// langStmt returns the statement setting lang at the start of fn, from the first of LangExprs whose identifiers are
+// all in scope there. The statement is nil if fn already has lang, as a parameter or by starting with lang := ...;
+// ok is false if no expression applies.
+func (l *Locer) langStmt(file *ast.File, fn *ast.FuncDecl) (stmt ast.Stmt, ok bool) {
+	if fn.Body == nil {
+		return nil, false
+	}
+	if l.inParams(fn, "lang") || hasLangStmt(fn.Body) {
+		return nil, true
+	}
+	for _, tmpl := range l.langExprs() {
+		expr, err := parser.ParseExpr(tmpl)
+		if err != nil {
+			continue // validated when configured
+		}
+		clearPos(expr)
+		applies := true
+		for _, id := range rootIdents(expr) {
+			applies = applies && l.inScope(file, fn, id)
+		}
+		if applies {
+			return &ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.Ident{Name: "lang"}},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{expr},
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// contextParam returns the name of the context.Context parameter of fn, if it has one.
+func contextParam(file *ast.File, fn *ast.FuncDecl) string {
+	ctxPkg := ""
+	for _, imp := range file.Imports {
+		if imp.Path.Value == strconv.Quote("context") {
+			ctxPkg = importName(imp)
+		}
+	}
+	if ctxPkg == "" || fn.Type.Params == nil {
+		return ""
+	}
+	for _, field := range fn.Type.Params.List {
+		sel, ok := field.Type.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Context" {
+			continue
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || x.Name != ctxPkg {
+			continue
+		}
+		for _, n := range field.Names {
+			if n.Name != "_" {
+				return n.Name
+			}
+		}
+	}
+	return ""
+}
+
+// clearPos zeroes the positions of n, which belong to another file, so it prints in place wherever it is inserted.
+func clearPos(n ast.Node) {
+	posType := reflect.TypeOf(token.NoPos)
+	ast.Inspect(n, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		v := reflect.ValueOf(n).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Type() == posType {
+				v.Field(i).SetInt(int64(token.NoPos))
+			}
+		}
+		return true
+	})
+}
+
+// hasLangStmt reports whether body already starts by setting lang.
+func hasLangStmt(body *ast.BlockStmt) bool {
+	if len(body.List) == 0 {
+		return false
+	}
+	if ass, ok := body.List[0].(*ast.AssignStmt); ok {
+		// todo: stronger check
+		if i, ok := ass.Lhs[0].(*ast.Ident); ok && i.Name == "lang" { // check/update generator
+			return true
+		}
+	}
+	return false
+}
+
+// rootIdents returns the identifiers expr refers to, leaving out selected fields and methods: s.lang(msg.From)
+// refers to s and msg.
+func rootIdents(expr ast.Expr) []string {
+	var ids []string
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.Ident:
+			ids = append(ids, n.Name)
+		}
+		return true
+	}
+	ast.Inspect(expr, visit)
+	return ids
+}
+
+// inParams reports whether name is the receiver, a parameter or a named result of fn.
+func (l *Locer) inParams(fn *ast.FuncDecl, name string) bool {
+	for _, fields := range []*ast.FieldList{fn.Recv, fn.Type.Params, fn.Type.Results} {
+		if fields == nil {
+			continue
+		}
+		for _, field := range fields.List {
+			for _, n := range field.Names {
+				if n.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// inScope reports whether name can be used at the start of the body of fn, in file. Only parameters and
+// package level names can be, besides goloc itself, which is imported when extracting.
+func (l *Locer) inScope(file *ast.File, fn *ast.FuncDecl, name string) bool {
+	if name == "goloc" || l.inParams(fn, name) {
+		return true
+	}
+	if l.info != nil {
+		if scope := l.info.Scopes[fn.Type]; scope != nil {
+			_, obj := scope.LookupParent(name, token.NoPos)
+			return obj != nil
+		}
+	}
+	for _, imp := range file.Imports {
+		if importName(imp) == name {
+			return true
+		}
+	}
+	if types.Universe.Lookup(name) != nil {
+		return true
+	}
+	_, ok := l.packageDecls(file)[name]
+	return ok
+}
+
+// importName returns the name imp is used by, guessing it from the path if not renamed.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	p, _ := strconv.Unquote(imp.Path.Value)
+	name := path.Base(p)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(p)) // a major version suffix
+	}
+	if i := strings.IndexAny(name, ".-"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// packageDecls returns the package level names declared by file and the other files of its package, in the same
+// directory. It is used when there is no type information.
+func (l *Locer) packageDecls(file *ast.File) map[string]struct{} {
+	dir := filepath.Dir(l.Fset.File(file.Pos()).Name())
+	key := dir + ":" + file.Name.Name
+	if decls, ok := l.decls[key]; ok {
+		return decls
+	}
+	if l.decls == nil {
+		l.decls = make(map[string]map[string]struct{})
+	}
+	decls := make(map[string]struct{})
+	addDecls(decls, file)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		Logger.Debug().Err(err).Msgf("cannot read package of %s", file.Name.Name)
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, e.Name()), nil, parser.SkipObjectResolution)
+		if err != nil || f.Name.Name != file.Name.Name {
+			continue
+		}
+		addDecls(decls, f)
+	}
+	l.decls[key] = decls
+	return decls
+}
+
+func addDecls(decls map[string]struct{}, f *ast.File) {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				decls[d.Name.Name] = struct{}{}
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						decls[n.Name] = struct{}{}
+					}
+				case *ast.TypeSpec:
+					decls[s.Name.Name] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+// needsLang reports whether extracting call adds a use of lang, so its function needs lang in scope.
+func (l *Locer) needsLang(call *ast.CallExpr) bool {
+	if l.extractable(call) {
+		return true
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "goloc" {
+		return false
+	}
+	switch sel.Sel.Name {
+	case "Add", "Addf":
+		return len(call.Args) > 0 && isStringLit(call.Args[0])
+	case "Addp":
+		return len(call.Args) >= 3 && isStringLit(call.Args[0]) && isStringLit(call.Args[1])
+	}
+	return false
+}
+
+// containsCall reports whether any call in n satisfies pred.
+func containsCall(n ast.Node, pred func(*ast.CallExpr) bool) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && pred(call) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func isStringLit(e ast.Expr) bool {
+	lit, ok := e.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING
+}
+
+// noLang records that the strings in fn, or outside of any function if fn is nil, cannot be extracted, as no lang
+// expression applies.
+func (l *Locer) noLang(pos token.Pos, fn *ast.FuncDecl) {
+	where := "outside of a function"
+	if fn != nil {
+		where = "func " + fn.Name.Name
+	}
+	msg := fmt.Sprintf("%s: %s", l.Fset.Position(pos), where)
+	Logger.Warn().Msgf("%s: no lang expression applies, add one with the identifiers in scope", msg)
+	l.NoLang = append(l.NoLang, msg)
+}
@@ -0,0 +1,228 @@
+package loc
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixLangExprs(t *testing.T) {
+	dir := t.TempDir()
+	src := `package bot
+
+import "context"
+
+type Server struct{}
+
+type Msg struct{ From string }
+
+func (s *Server) lang(from string) string { return from }
+
+func Send(text string) {}
+
+func withCtx(ctx context.Context) {
+	Send("from ctx")
+}
+
+func (s *Server) handle(msg *Msg) {
+	Send("from msg")
+}
+
+func withU(u string) {
+	Send("from u")
+}
+
+func withLang(lang string) {
+	Send("from lang")
+}
+
+func none() {
+	Send("nowhere")
+}
+`
+	for name, data := range map[string]string{
+		"bot.go":  src,
+		"lang.go": "package bot\n\nfunc getLang(u string) string { return u }\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	l := &Locer{
+		DefaultLang: "en-GB",
+		Funcs:       map[string]struct{}{},
+		Fmtfuncs:    map[string]struct{}{"Send": {}},
+		LangExprs:   []string{"goloc.LangFrom(ctx)", "s.lang(msg.From)", "getLang(u)"},
+		Checked:     make(map[string]struct{}),
+		Fset:        token.NewFileSet(),
+		Apply:       true,
+		TransDir:    filepath.Join(dir, "trans"),
+	}
+	if err := l.Handle([]string{"bot.go"}, l.Fix); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "bot.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, ok := range map[string]bool{
+		"lang := goloc.LangFrom(ctx)\n\tSend(goloc.Trnl(lang, \"bot.go:1\"))": true,
+		"lang := s.lang(msg.From)\n\tSend(goloc.Trnl(lang, \"bot.go:2\"))":    true,
+		"lang := getLang(u)\n\tSend(goloc.Trnl(lang, \"bot.go:3\"))":          true,
+		"(lang string) {\n\tSend(goloc.Trnl(lang, \"bot.go:4\"))":             true,
+		`Send("nowhere")`:       true,
+		"getLang(u)\n\tlang :=": false,
+	} {
+		if strings.Contains(string(got), want) != ok {
+			t.Errorf("contains %q = %v, want %v:\n%s", want, !ok, ok, got)
+		}
+	}
+	if len(l.NoLang) != 1 || !strings.HasSuffix(l.NoLang[0], "func none") {
+		t.Errorf("NoLang = %q, want func none", l.NoLang)
+	}
+}
+
+func TestFixUseContext(t *testing.T) {
+	dir := t.TempDir()
+	src := `package bot
+
+import stdctx "context"
+
+func Send(text string)                                    {}
+func Sendp(one, other string, n int, args ...interface{}) {}
+
+func getLang(u string) string { return u }
+
+func handle(ctx stdctx.Context, n int) {
+	Send("hello")
+	Sendp("%d file", "%d files", n)
+}
+
+func legacy(u string) {
+	Send("hello")
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "bot.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	l := &Locer{
+		DefaultLang: "en-GB",
+		Fmtfuncs:    map[string]struct{}{"Send": {}},
+		Pluralfuncs: map[string]struct{}{"Sendp": {}},
+		UseContext:  true,
+		Checked:     make(map[string]struct{}),
+		Fset:        token.NewFileSet(),
+		Apply:       true,
+		TransDir:    filepath.Join(dir, "trans"),
+	}
+	if err := l.Handle([]string{"bot.go"}, l.Fix); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "bot.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, ok := range map[string]bool{
+		"n int) {\n\tSend(goloc.T(ctx, \"bot.go:1\"))":                                 true,
+		`Send(goloc.Tpf(ctx, "bot.go:2", n, map[string]string{"1": strconv.Itoa(n)}))`: true,
+		"lang := getLang(u)\n\tSend(goloc.Trnl(lang, \"bot.go:1\"))":                   true,
+	} {
+		if strings.Contains(string(got), want) != ok {
+			t.Errorf("contains %q = %v, want %v:\n%s", want, !ok, ok, got)
+		}
+	}
+}
+
+func TestRootIdents(t *testing.T) {
+	for expr, want := range map[string]string{
+		"getLang(u)":                "getLang u",
+		"s.lang(msg.From)":          "s msg",
+		"goloc.LangFrom(ctx)":       "goloc ctx",
+		"langs[u.ID].Tag.String()":  "langs u",
+		"pick(r.Header, \"en-GB\")": "pick r",
+	} {
+		e, err := parser.ParseExpr(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(rootIdents(e), " "); got != want {
+			t.Errorf("rootIdents(%s) = %s, want %s", expr, got, want)
+		}
+	}
+}
+
+func TestFixAddpShortCall(t *testing.T) {
+	dir := t.TempDir()
+	src := `package bot
+
+import "github.com/PaulSonOfLars/goloc"
+
+func Send(text string) {}
+
+func handle(lang string, n int) {
+	Send(goloc.Addp("x"))
+	Send(goloc.Addp("%d file", "%d files"))
+	Send(goloc.Addp("%d file", "%d files", n))
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "bot.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	l := &Locer{
+		DefaultLang: "en-GB",
+		Fmtfuncs:    map[string]struct{}{"Send": {}},
+		Checked:     make(map[string]struct{}),
+		Fset:        token.NewFileSet(),
+		Apply:       true,
+		TransDir:    filepath.Join(dir, "trans"),
+	}
+	if err := l.Handle([]string{"bot.go"}, l.Fix); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "bot.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, ok := range map[string]bool{
+		`Send(goloc.Addp("x"))`:                   true,
+		`Send(goloc.Addp("%d file", "%d files"))`: true,
+		`goloc.Addp("%d file", "%d files", n)`:    false,
+	} {
+		if strings.Contains(string(got), want) != ok {
+			t.Errorf("contains %q = %v, want %v:\n%s", want, !ok, ok, got)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package loc
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -10,10 +12,12 @@ import (
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"go/types"
+	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -25,83 +29,258 @@ import (
 	"golang.org/x/tools/go/ast/astutil"
 )
 
-const translationDir = "trans"
+// runtimeImport is the package extracted and generated code calls into, as goloc.
+const runtimeImport = "github.com/PaulSonOfLars/goloc"
+
+// DefaultTranslationDir is the directory translation files are read from and written to, unless configured otherwise.
+const DefaultTranslationDir = "trans"
+
+// DefaultLanguage is the language the goloc command extracts strings into, unless configured otherwise. The runtime
+// falls back to RuntimeDefaultLanguage until SetDefaultLang is called.
+const DefaultLanguage = "en-GB"
+
+// RuntimeDefaultLanguage is the default language of the package level functions, unless set with SetDefaultLang.
+const RuntimeDefaultLanguage = "en-US"
+
+// Key strategies, naming the keys of extracted strings.
+const (
+	KeyCounter = "counter" // file.go:n, numbered in extraction order
+	KeyHash    = "hash"    // file.go:h, h being a hash of the default text, so keys survive strings moving around
+)
 
 var bufs = pool.NewBufferFactory()
 
 type Translation struct {
-	XMLName xml.Name `xml:"translation"`
-	Rows    []Value
-	Counter int
+	XMLName xml.Name `xml:"translation" json:"-" yaml:"-"`
+	Rows    []Value  `json:"rows" yaml:"rows"`
+	Counter int      `json:"counter" yaml:"counter"`
 }
 
 type Value struct {
-	Id      int    `xml:"id,attr"`
-	Name    string `xml:"name,attr"`
-	Value   string `xml:"value"`
-	Comment string `xml:",comment"`
+	Id      int      `xml:"id,attr" json:"id" yaml:"id"`
+	Name    string   `xml:"name,attr" json:"name" yaml:"name"`
+	State   string   `xml:"state,attr,omitempty" json:"state,omitempty" yaml:"state,omitempty"` // review state from translation tools; see StateReviewed
+	Value   string   `xml:"value" json:"value" yaml:"value"`
+	Plurals []Plural `xml:"plural,omitempty" json:"plurals,omitempty" yaml:"plurals,omitempty"`
+	Select  *Select  `xml:"select,omitempty" json:"select,omitempty" yaml:"select,omitempty"`
+	Comment string   `xml:",comment" json:"comment,omitempty" yaml:"comment,omitempty"`
 }
 
+// Translation states exchanged with translation tools. Only StateReviewed is stored; the others follow from
+// whether a value has text.
+const (
+	StateNew        = "new"
+	StateTranslated = "translated"
+	StateReviewed   = "reviewed"
+)
+
 type Locer struct {
 	DefaultLang string
 	Funcs       map[string]struct{}
 	Fmtfuncs    map[string]struct{}
+	// Pluralfuncs are called as f(one, other string, n int, args...), with both strings formatted from n onwards.
+	Pluralfuncs map[string]struct{}
 	Checked     map[string]struct{}
 	OrderedVals []string
 	Fset        *token.FileSet
 	Apply       bool
 	Counter     int64
+	// TransDir is the root of the translation files; DefaultTranslationDir is used if empty.
+	TransDir string
+	// ICU makes check validate values as ICU MessageFormat messages instead of {n} placeholders.
+	ICU bool
+	// FormatNumbers makes extract turn %d into {n,number} placeholders, formatted for each language by Trnlf.
+	FormatNumbers bool
+	// Diagnostics collects the problems found by Check and CheckAll.
+	Diagnostics []Diagnostic
+	// FileFormat is the name of the FileFormat translation files are written in; XML is used if empty.
+	FileFormat string
+	// Generated makes Handle include generated files, marked with a "// Code generated ... DO NOT EDIT." comment.
+	Generated bool
+	// Tests makes Handle include _test.go files.
+	Tests bool
+	// Include and Exclude are globs selecting the source files Handle works on; see matchGlob.
+	Include []string
+	Exclude []string
+	// Tags, GOOS and GOARCH are the build constraints source files must satisfy; GOOS and GOARCH default to the
+	// current platform.
+	Tags   []string
+	GOOS   string
+	GOARCH string
+	// KeyStrategy is how extracted strings are named; KeyCounter is used if empty.
+	KeyStrategy string
+	// Rules overrides the severity of check rules, by name; "off" drops their findings.
+	Rules map[string]string
+	// LangExprs are the Go expressions extract sets lang to, in functions which don't have it yet, such as
+	// goloc.LangFrom(ctx) or s.lang(msg.From). The first one whose identifiers are all in scope is used; see
+	// DefaultLangExprs.
+	LangExprs []string
+	// UseContext makes extract convert calls in functions with a context.Context parameter to goloc.T, Tf and Tpf
+	// with that context, instead of setting lang; see WithLang.
+	UseContext bool
+	// NoLang holds the functions whose strings Fix left alone, as none of LangExprs applies in them.
+	NoLang []string
+	// Skipped holds the files Handle did not work on, along with the reason.
+	Skipped map[string]string
+	// Catalog holds the translations loaded while extracting and checking; a fresh one is used if nil.
+	Catalog *Catalog
+
+	info     *types.Info                    // type information of the file being handled, if loaded
+	decls    map[string]map[string]struct{} // package level names by dir:package, see packageDecls
+	ctxParam string                         // context parameter of the function being converted, if UseContext
+
+	// the strings of the module being extracted, set by startExtraction.
+	newData      map[string]map[string]map[string]Value // locale:(filename:(trigger:Value))
+	newDataNames map[string][]string                    // filename:[]newtriggers
+	noDupStrings map[string]string                      // map of currently loaded strings, to avoid duplicates and reduce translation efforts
+}
+
+func (l *Locer) catalog() *Catalog {
+	if l.Catalog == nil {
+		l.Catalog = NewCatalog(l.DefaultLang)
+		l.Catalog.SetDir(l.transDir())
+		l.Catalog.SetFileFormat(l.fileFormat())
+	}
+	return l.Catalog
+}
+
+// ValidateKeyStrategy returns an error if name is not a key strategy.
+func ValidateKeyStrategy(name string) error {
+	switch name {
+	case "", KeyCounter, KeyHash:
+		return nil
+	}
+	return fmt.Errorf("unknown key strategy '%s'", name)
+}
+
+// newKey returns the key of a new string of module name, with id and default text.
+func (l *Locer) newKey(name string, id int, text string) string {
+	if l.KeyStrategy == KeyHash {
+		sum := sha256.Sum256([]byte(text))
+		return name + ":" + hex.EncodeToString(sum[:4])
+	}
+	return name + ":" + strconv.Itoa(id)
+}
+
+func (l *Locer) fileFormat() FileFormat {
+	f, err := FileFormatByName(l.FileFormat)
+	if err != nil {
+		Logger.Fatal().Err(err).Send()
+	}
+	return f
 }
 
+func (l *Locer) transDir() string {
+	if l.TransDir == "" {
+		return DefaultTranslationDir
+	}
+	return l.TransDir
+}
+
+// Handle parses the files, directories and package patterns (such as ./...) in args, and calls hdnl on each file.
+// Files are skipped if they are generated or tests, unless Generated or Tests are set, if they don't match the
+// Include and Exclude globs, or if their build constraints exclude them; see Skipped.
 func (l *Locer) Handle(args []string, hdnl func(*ast.File)) error {
 	if len(args) == 0 {
 		Logger.Error().Msg("No input provided.")
 		return nil
 	}
+	if l.needsTypes() {
+		// qualified funcs can only be told apart with type information.
+		if err := l.handlePackages(args, hdnl); err != nil {
+			return err
+		}
+		args = nil
+	}
+
+	var patterns []string
 	for _, arg := range args {
+		if l.isPattern(arg) {
+			patterns = append(patterns, arg)
+			continue
+		}
 		fi, err := os.Stat(arg)
 		if err != nil {
-			return err
+			return missingArg(arg, err)
 		}
 
 		switch mode := fi.Mode(); {
 		case mode.IsDir():
 			// do directory stuff
 			Logger.Debug().Msg("directory input")
+			filter := func(fi fs.FileInfo) bool {
+				name := filepath.Join(arg, fi.Name())
+				if reason := l.skipReason(name); reason != "" {
+					l.skip(name, reason)
+					return false
+				}
+				return true
+			}
 			var nodes map[string]*ast.Package
-			nodes, err = parser.ParseDir(l.Fset, arg, nil, parser.ParseComments)
+			nodes, err = parser.ParseDir(l.Fset, arg, filter, parser.ParseComments)
 			if err != nil {
 				return err
 			}
 			for _, n := range nodes {
 				for _, f := range n.Files {
-					name := l.Fset.File(f.Pos()).Name()
-					if _, ok := l.Checked[name]; ok {
-						continue // todo: check for file name clashes in diff packages?
-					}
-					l.Checked[name] = struct{}{}
-					hdnl(f)
+					l.handleFile(l.Fset.File(f.Pos()).Name(), f, hdnl)
 				}
 			}
 		case mode.IsRegular():
 			// do file stuff
 			Logger.Debug().Msg("file input")
+			if reason := l.skipReason(arg); reason != "" {
+				l.skip(arg, reason)
+				continue
+			}
 			node, err := parser.ParseFile(l.Fset, arg, nil, parser.ParseComments)
 			if err != nil {
 				return err
 			}
-			name := l.Fset.File(node.Pos()).Name()
-			if _, ok := l.Checked[name]; ok {
-				continue // todo: check for file name clashes in diff packages?
+			l.handleFile(l.Fset.File(node.Pos()).Name(), node, hdnl)
+		}
+	}
+
+	if len(patterns) > 0 {
+		Logger.Debug().Msg("package pattern input")
+		files, err := l.loadPatterns(patterns)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			file = moduleName(file)
+			if reason := l.skipReason(file); reason != "" {
+				l.skip(file, reason)
+				continue
+			}
+			node, err := parser.ParseFile(l.Fset, file, nil, parser.ParseComments)
+			if err != nil {
+				return err
 			}
-			l.Checked[name] = struct{}{}
-			hdnl(node)
+			l.handleFile(l.Fset.File(node.Pos()).Name(), node, hdnl)
 		}
 	}
 	Logger.Info().Msg("the following have been checked:")
 	for k := range l.Checked {
 		Logger.Info().Msg("  " + k)
 	}
+	if len(l.NoLang) > 0 {
+		Logger.Warn().Msg("strings were left in the following, as no lang expression applies:")
+		for _, fn := range l.NoLang {
+			Logger.Warn().Msg("  " + fn)
+		}
+	}
+	if len(l.Skipped) > 0 {
+		skipped := make([]string, 0, len(l.Skipped))
+		for k := range l.Skipped {
+			skipped = append(skipped, k)
+		}
+		sort.Strings(skipped)
+		Logger.Info().Msg("the following have been skipped:")
+		for _, k := range skipped {
+			Logger.Info().Msg("  " + k + ": " + l.Skipped[k])
+		}
+	}
 	return nil
 }
 
@@ -170,9 +349,13 @@ func (l *Locer) Visit(n ast.Node) ast.Visitor {
 		}
 	case *ast.FuncDecl:
 		slog := l.functionSublogger(x)
-		_, funcOK := l.Funcs[x.Name.Name]
-		_, fmtOK := l.Fmtfuncs[x.Name.Name]
-		if funcOK || fmtOK {
+		name := x.Name.Name
+		if l.info != nil {
+			if fn, ok := l.info.Defs[x.Name].(*types.Func); ok {
+				name = fn.FullName()
+			}
+		}
+		if inFuncs(l.Funcs, name) || inFuncs(l.Fmtfuncs, name) || inFuncs(l.Pluralfuncs, name) {
 			slog.Debug().Msg("found a function in our list")
 			return l
 		}
@@ -314,14 +497,114 @@ func (l *Locer) Inspect(node *ast.File) {
 
 }
 
-var newData map[string]map[string]map[string]Value // locale:(filename:(trigger:Value))
-var newDataNames map[string][]string               // filename:[]newtriggers
-var noDupStrings map[string]string                 // map of currently loaded strings, to avoid duplicates and reduce translation efforts
+// extractable reports whether convertCall converts call, without storing its strings.
+func (l *Locer) extractable(call *ast.CallExpr) bool {
+	if calleeIdent(call) == nil || len(call.Args) == 0 {
+		return false
+	}
+	if _, ok := l.matchFunc(l.Pluralfuncs, call); ok && len(call.Args) >= 3 {
+		return isStringLit(call.Args[0]) && isStringLit(call.Args[1])
+	}
+	_, funcOK := l.matchFunc(l.Funcs, call)
+	_, fmtOK := l.matchFunc(l.Fmtfuncs, call)
+	return (funcOK || fmtOK) && isStringLit(call.Args[0])
+}
+
+// convertCall returns the goloc version of a call to one of the funcs, fmtfuncs or pluralfuncs, along with the
+// imports its arguments need. ok is false if call is not to one of them, or doesn't pass string literals.
+// The new strings are added to newData; call itself is left untouched.
+func (l *Locer) convertCall(name string, call *ast.CallExpr) (out *ast.CallExpr, imports []string, ok bool, err error) {
+	id := calleeIdent(call)
+	if id == nil || len(call.Args) == 0 {
+		return nil, nil, false, nil
+	}
+	funcEntry, funcOK := l.matchFunc(l.Funcs, call)
+	fmtEntry, fmtOK := l.matchFunc(l.Fmtfuncs, call)
+	pluralEntry, pluralOK := l.matchFunc(l.Pluralfuncs, call)
+
+	var args *ast.CallExpr
+	var newName string
+	switch {
+	case pluralOK && len(call.Args) >= 3:
+		one, oneOK := call.Args[0].(*ast.BasicLit)
+		other, otherOK := call.Args[1].(*ast.BasicLit)
+		if !oneOK || !otherOK || one.Kind != token.STRING || other.Kind != token.STRING {
+			Logger.Debug().Msgf("plural call to %s does not use string literals", id.Name)
+			return nil, nil, false, nil
+		}
+		Logger.Debug().Msgf("found plural strings in funcname %s: %s / %s", id.Name, one.Value, other.Value)
+
+		args, imports, err = l.injectPlural(name, call, one, other)
+		newName = l.getUnPluralFunc(pluralEntry, id.Name)
+	case funcOK || fmtOK:
+		firstArg := call.Args[0]
+		if litItem, ok := firstArg.(*ast.BasicLit); ok && litItem.Kind == token.STRING {
+			Logger.Debug().Msgf("found a string in funcname %s:\n%s", id.Name, litItem.Value)
+
+			entry := fmtEntry
+			if funcOK {
+				entry = funcEntry
+			}
+			args, imports, err = l.injectTran(name, call, funcOK, litItem)
+			newName = l.getUnFmtFunc(entry, id.Name)
+		} else if binExpr, ok := firstArg.(*ast.BinaryExpr); ok && binExpr.Op == token.ADD {
+			// note: plz reformat not to use adds
+			Logger.Debug().Msg("found a binary expr instead of str; fix your code")
+			return nil, nil, false, nil
+		} else {
+			Logger.Debug().Msgf("found something else: %T", firstArg)
+			return nil, nil, false, nil
+		}
+	default:
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	return &ast.CallExpr{
+		Fun:    renamedFun(call, id, newName),
+		Lparen: call.Lparen,
+		Args:   []ast.Expr{args},
+		Rparen: call.Rparen,
+	}, imports, true, nil
+}
+
+// renamedFun returns the function call calls, renamed to newName; id is the identifier naming it.
+func renamedFun(call *ast.CallExpr, id *ast.Ident, newName string) ast.Expr {
+	fun := &ast.Ident{NamePos: id.NamePos, Name: newName}
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+		newSel := *sel
+		newSel.Sel = fun
+		return &newSel
+	}
+	return fun
+}
+
+// startExtraction loads the current values of module name, and resets the strings extracted from it.
+func (l *Locer) startExtraction(name string) *Catalog {
+	cat := l.catalog()
+	cat.Load(name) // load current values
+	Logger.Debug().Msgf("module count at %d", cat.Count(name))
+	l.newData = make(map[string]map[string]map[string]Value)
+	l.newDataNames = make(map[string][]string)
+	l.noDupStrings = make(map[string]string)
+
+	// make sure default language is loaded
+	l.newData[l.DefaultLang] = make(map[string]map[string]Value)
+	l.newData[l.DefaultLang][name] = make(map[string]Value)
+	// initialise set for all other languages
+	for _, k := range cat.Languages() { // initialise all languages
+		l.newData[k] = make(map[string]map[string]Value)
+		l.newData[k][name] = make(map[string]Value)
+	}
+	return cat
+}
 
 // todo: ensure import works as expected
 
 func (l *Locer) Fix(node *ast.File) {
-	name := l.Fset.File(node.Pos()).Name()
+	name := moduleName(l.Fset.File(node.Pos()).Name())
 	loadModuleExpr := &ast.ExprStmt{
 		X: &ast.CallExpr{
 			Fun: &ast.SelectorExpr{
@@ -339,25 +622,24 @@ func (l *Locer) Fix(node *ast.File) {
 
 	// todo: investigate unnecessary "lang := " loads
 
-	Load(name) // load current values
-	Logger.Debug().Msgf("module count at %d", dataCount[name])
-	newData = make(map[string]map[string]map[string]Value) // locale:(filename:(trigger:Value))
-	newDataNames = make(map[string][]string)               // filename:[]newtriggers
-	noDupStrings = make(map[string]string)                 // map of currently loaded strings, to avoid duplicates and reduce translation efforts
+	cat := l.startExtraction(name)
+	defer func() { l.ctxParam = "" }()
 
-	// make sure default language is loaded
-	newData[l.DefaultLang] = make(map[string]map[string]Value)
-	newData[l.DefaultLang][name] = make(map[string]Value)
-	// initialise set for all other languages
-	for k := range data { // initialise all languages
-		newData[k] = make(map[string]map[string]Value)
-		newData[k][name] = make(map[string]Value)
-	}
+	var needsLangSetting bool                // method needs the lang := arg
+	var funcLang ast.Stmt                    // statement setting lang in the current method, if it needs one
+	langOK := true                           // lang can be set in the current method
+	var needGolocImport bool                 // goloc needs importing
+	needImports := make(map[string]struct{}) // other imports needed by converted format args, eg strconv
+	var initExists bool                      // does init method exist
 
-	var needsLangSetting bool  // method needs the lang := arg
-	var needGolocImport bool   // goloc needs importing
-	var needStrconvImport bool // need to import strconv
-	var initExists bool        // does init method exist
+	addImports := func(imports []string) {
+		for _, imp := range imports {
+			needImports[imp] = struct{}{}
+		}
+	}
+	reportErr := func(callExpr *ast.CallExpr, funcName string, err error) {
+		Logger.Error().Err(err).Msgf("%s: cannot extract call to %s", l.Fset.Position(callExpr.Pos()), funcName)
+	}
 
 	// should return to node?
 	astutil.Apply(node,
@@ -369,73 +651,74 @@ func (l *Locer) Fix(node *ast.File) {
 				if ret.Name.Name == "init" {
 					initExists = true
 				}
+				needsLangSetting = false
+				funcLang, langOK = nil, true
+				l.ctxParam = ""
+				if containsCall(ret, l.needsLang) {
+					if l.UseContext {
+						l.ctxParam = contextParam(node, ret)
+					}
+					if l.ctxParam != "" {
+						return true // calls use the context instead of lang
+					}
+					if funcLang, langOK = l.langStmt(node, ret); !langOK {
+						l.noLang(ret.Pos(), ret)
+					}
+				}
+
+			} else if d, ok := n.(*ast.GenDecl); ok && cursor.Parent() == node {
+				funcLang, langOK = nil, !containsCall(d, l.needsLang)
+				l.ctxParam = ""
+				if !langOK {
+					l.noLang(d.Pos(), nil)
+				}
 
 				// Check method calls
 			} else if callExpr, ok := n.(*ast.CallExpr); ok {
-				// determine if method is one of the validated ones
+				if !langOK && l.needsLang(callExpr) {
+					return true // reported already; the string stays
+				}
+				// determine if method is one of the validated ones; if valid and has args, check first arg (which
+				// should be a string)
+				newCall, imports, ok, err := l.convertCall(name, callExpr)
+				if err != nil {
+					reportErr(callExpr, types.ExprString(callExpr.Fun), err)
+					return true
+				} else if ok {
+					cursor.Replace(newCall)
+					addImports(imports)
+					needGolocImport = true
+					needsLangSetting = true
+					return false
+				}
+
 				if funcCall, ok := callExpr.Fun.(*ast.SelectorExpr); ok {
 					Logger.Debug().Msg("found random call named " + funcCall.Sel.Name)
 
-					// if valid and has args, check first arg (which should be a string)
-					_, funcOK := l.Funcs[funcCall.Sel.Name]
-					_, fmtOK := l.Fmtfuncs[funcCall.Sel.Name]
-					if funcOK || fmtOK && len(callExpr.Args) > 0 {
-						firstArg := callExpr.Args[0]
-
-						if litItem, ok := firstArg.(*ast.BasicLit); ok && litItem.Kind == token.STRING {
-							buf := bytes.NewBuffer([]byte{})
-							if err := printer.Fprint(buf, l.Fset, litItem); err != nil {
-								Logger.Fatal().Err(err).Send()
-							}
-							Logger.Debug().Msgf("found a string in funcname %s:\n%s", funcCall.Sel.Name, buf.String())
-
-							args, needStrconvImportNew := l.injectTran(name, callExpr, funcCall, litItem)
-
-							funcCall.Sel.Name = l.getUnFmtFunc(funcCall.Sel.Name)
-							callExpr.Fun = funcCall
-							callExpr.Args = []ast.Expr{args}
-							cursor.Replace(callExpr)
-							needStrconvImport = needStrconvImportNew
-							needGolocImport = true
-							needsLangSetting = true
-							return false
-
-							// if not a string, but a binop:
-						} else if binExpr, ok := firstArg.(*ast.BinaryExpr); ok && binExpr.Op == token.ADD {
-							// note: plz reformat not to use adds
-							Logger.Debug().Msg("found a binary expr instead of str; fix your code")
-
-						} else {
-							Logger.Debug().Msgf("found something else: %T", firstArg)
-						}
-					} else if caller, ok := funcCall.X.(*ast.Ident); ok && caller.Name == "goloc" {
+					if caller, ok := funcCall.X.(*ast.Ident); ok && caller.Name == "goloc" {
 						// has already been translated, check if it isn't duplicated.
-						if funcCall.Sel.Name == "Trnl" || funcCall.Sel.Name == "Trnlf" {
+						switch funcCall.Sel.Name {
+						case "Trnl", "Trnlf", "Trnp", "Trnpf", "T", "Tf", "Tp", "Tpf":
 							if arg, ok := callExpr.Args[1].(*ast.BasicLit); ok && arg.Kind == token.STRING { // possible OOB
 								val, err := strconv.Unquote(arg.Value)
 								if err != nil {
 									Logger.Fatal().Err(err).Send()
 									return true
 								}
-								itemName, ok := noDupStrings[data[l.DefaultLang][val].Value]
+								defLangVal, _ := cat.Lookup(l.DefaultLang, val)
+								itemName, ok := l.noDupStrings[dedupKey(defLangVal)]
 								if ok {
 									val = itemName
 								} else {
-									noDupStrings[data[l.DefaultLang][val].Value] = val
+									l.noDupStrings[dedupKey(defLangVal)] = val
 									// add curr data to the new data (this will remove unused vals)
-									for lang := range newData {
-										currVal, ok := data[lang][val]
+									for lang := range l.newData {
+										currVal, ok := cat.Lookup(lang, val)
 										if !ok {
-											defLangVal := data[l.DefaultLang][val]
-											currVal = Value{
-												Id:      defLangVal.Id,
-												Name:    defLangVal.Name,
-												Value:   "",
-												Comment: defLangVal.Value,
-											}
+											currVal = untranslated(lang, defLangVal)
 											// add to old data list, so its added at the start and offsets aren't changed.
 										}
-										newData[lang][name][val] = currVal
+										l.newData[lang][name][val] = currVal
 									}
 								}
 
@@ -443,15 +726,41 @@ func (l *Locer) Fix(node *ast.File) {
 								cursor.Replace(n)
 								return false
 							}
-						} else if funcCall.Sel.Name == "Add" || funcCall.Sel.Name == "Addf" {
+						case "Add", "Addf":
 							if v, ok := callExpr.Args[0].(*ast.BasicLit); ok {
 								buf := bytes.NewBuffer([]byte{})
 								printer.Fprint(buf, l.Fset, v)
 								Logger.Debug().Msgf("found a string to add via Add(f):\n%s", buf.String())
 
-								callExpr, needStrconvImport = l.injectTran(name, callExpr, funcCall, v)
+								newCall, imports, err := l.injectTran(name, callExpr, funcCall.Sel.Name == "Addf", v)
+								if err != nil {
+									reportErr(callExpr, funcCall.Sel.Name, err)
+									return true
+								}
 
-								cursor.Replace(callExpr)
+								cursor.Replace(newCall)
+								addImports(imports)
+								needGolocImport = true
+								needsLangSetting = true
+								return false
+							}
+						case "Addp":
+							if len(callExpr.Args) < 3 {
+								break
+							}
+							one, oneOK := callExpr.Args[0].(*ast.BasicLit)
+							other, otherOK := callExpr.Args[1].(*ast.BasicLit)
+							if oneOK && otherOK {
+								Logger.Debug().Msgf("found plural strings to add via Addp: %s / %s", one.Value, other.Value)
+
+								newCall, imports, err := l.injectPlural(name, callExpr, one, other)
+								if err != nil {
+									reportErr(callExpr, funcCall.Sel.Name, err)
+									return true
+								}
+
+								cursor.Replace(newCall)
+								addImports(imports)
 								needGolocImport = true
 								needsLangSetting = true
 								return false
@@ -465,38 +774,10 @@ func (l *Locer) Fix(node *ast.File) {
 		},
 		/*post*/
 		func(cursor *astutil.Cursor) bool {
-			if FuncDecl, ok := cursor.Node().(*ast.FuncDecl); ok && needsLangSetting {
-				if initExists && FuncDecl.Name.Name == "init" && needGolocImport {
-					if !initHasLoad(FuncDecl, name) {
-						FuncDecl.Body.List = append(FuncDecl.Body.List, loadModuleExpr)
-						cursor.Replace(FuncDecl)
-					}
-				}
-
-				if len(FuncDecl.Body.List) == 0 {
-					return true // do nothing
-				} else if ass, ok := FuncDecl.Body.List[0].(*ast.AssignStmt); ok {
-					// todo: stronger check
-					if i, ok := ass.Lhs[0].(*ast.Ident); ok && i.Name == "lang" { // check/update generator
-						return true // continue and ignore
-					}
-				}
-
-				Logger.Debug().Msg("adding lang to " + name)
-				FuncDecl.Body.List = append([]ast.Stmt{
-					&ast.AssignStmt{
-						Lhs: []ast.Expr{&ast.Ident{Name: "lang"}},
-						Tok: token.DEFINE,
-						Rhs: []ast.Expr{
-							&ast.CallExpr{
-								Fun:  &ast.Ident{Name: "getLang"},       // todo: parameterise
-								Args: []ast.Expr{&ast.Ident{Name: "u"}}, // todo: figure this bit out
-							},
-						},
-					},
-				}, FuncDecl.Body.List...)
+			if FuncDecl, ok := cursor.Node().(*ast.FuncDecl); ok && needsLangSetting && funcLang != nil {
+				Logger.Debug().Msg("adding lang to " + FuncDecl.Name.Name)
+				FuncDecl.Body.List = append([]ast.Stmt{funcLang}, FuncDecl.Body.List...)
 				cursor.Replace(FuncDecl)
-				needsLangSetting = false
 			}
 			return true
 		},
@@ -512,24 +793,22 @@ func (l *Locer) Fix(node *ast.File) {
 				Body: &ast.BlockStmt{List: []ast.Stmt{loadModuleExpr}},
 			}
 			cursor.InsertAfter(v)
-		} else if ret, ok := cursor.Node().(*ast.FuncDecl); ok && needsLangSetting {
-			if initExists && ret.Name.Name == "init" && needGolocImport {
-				if !initHasLoad(ret, name) {
-					ret.Body.List = append(ret.Body.List, loadModuleExpr)
-					cursor.Replace(ret)
-				}
+		} else if ret, ok := cursor.Node().(*ast.FuncDecl); ok && ret.Recv == nil && ret.Name.Name == "init" && needGolocImport {
+			if !initHasLoad(ret, name) {
+				ret.Body.List = append(ret.Body.List, loadModuleExpr)
+				cursor.Replace(ret)
 			}
 		}
 		return true
 	})
 
 	if needGolocImport {
-		astutil.AddImport(l.Fset, node, "github.com/PaulSonOfLars/goloc")
+		astutil.AddImport(l.Fset, node, runtimeImport)
 		ast.SortImports(l.Fset, node)
 	}
 
-	if needStrconvImport {
-		astutil.AddImport(l.Fset, node, "strconv")
+	for imp := range needImports {
+		astutil.AddImport(l.Fset, node, imp)
 		ast.SortImports(l.Fset, node)
 	}
 
@@ -549,69 +828,39 @@ func (l *Locer) Fix(node *ast.File) {
 		Logger.Fatal().Err(err).Send()
 		return
 	}
-	if err := l.saveMap(newData, newDataNames); err != nil {
+	if err := l.saveMap(l.newData, l.newDataNames); err != nil {
 		Logger.Fatal().Err(err).Send()
 		return
 	}
 }
 
 func (l *Locer) Create(args []string, lang language.Tag) {
-	err := filepath.Walk(path.Join(translationDir, l.DefaultLang),
-		func(fpath string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-
-			f, err := os.Open(fpath)
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-			dec := xml.NewDecoder(f)
-			var xmlData Translation
-			err = dec.Decode(&xmlData)
-			if err != nil {
-				return err
-			}
-
-			for i := 0; i < len(xmlData.Rows); i++ {
-				xmlData.Rows[i].Comment = xmlData.Rows[i].Value
-				xmlData.Rows[i].Value = ""
-			}
-
-			filename := strings.Replace(fpath, sep(l.DefaultLang), sep(lang.String()), 1)
+	fsys := os.DirFS(l.transDir())
+	format := l.fileFormat()
+	err := walkModules(fsys, l.DefaultLang, func(file string) error {
+		xmlData, err := readModule(fsys, format, l.DefaultLang, file)
+		if err != nil {
+			return err
+		}
 
-			if err := os.MkdirAll(path.Dir(filename), 0755); err != nil {
-				return err
-			}
+		for i := 0; i < len(xmlData.Rows); i++ {
+			xmlData.Rows[i] = untranslated(lang.String(), xmlData.Rows[i])
+		}
 
-			newF, err := os.Create(filename)
-			if err != nil {
-				return err
-			}
-			defer newF.Close()
-			newF.WriteString(xml.Header)
-			enc := xml.NewEncoder(newF)
-			enc.Indent("", "    ")
-			if err := enc.Encode(xmlData); err != nil {
-				return err
-			}
-			newF.WriteString("\n")
-			return nil
-		})
+		// new languages are written in the preferred format, wherever the default one is stored.
+		return writeModuleFile(format, filepath.Join(l.transDir(), moduleFile(format, lang.String(), file)), xmlData)
+	})
 	if err != nil {
 		Logger.Fatal().Err(err).Send()
 	}
 }
 
 func (l *Locer) CheckAll() error {
-	LoadAll(l.DefaultLang)
+	cat := l.catalog()
+	cat.LoadAll(l.DefaultLang)
 
 	v := getHTMLValidator()
-	for lang := range data {
+	for _, lang := range cat.Languages() {
 		if err := l.check(v, lang); err != nil {
 			return err
 		}
@@ -620,8 +869,9 @@ func (l *Locer) CheckAll() error {
 }
 
 func (l *Locer) Check(lang string) error {
-	LoadLangAll(l.DefaultLang)
-	LoadLangAll(lang)
+	cat := l.catalog()
+	cat.LoadLangAll(l.DefaultLang)
+	cat.LoadLangAll(lang)
 
 	err := l.check(getHTMLValidator(), lang)
 	if err != nil {
@@ -651,45 +901,96 @@ func getHTMLValidator() htmlcheck.Validator {
 }
 
 func (l *Locer) check(v htmlcheck.Validator, lang string) error {
-	if lang == l.DefaultLang { // don't check default
+	cat := l.catalog()
+	values := cat.Values(lang)
+	keys := make([]string, 0, len(values))
+	for s := range values {
+		keys = append(keys, s)
+	}
+	sort.Strings(keys)
+
+	if lang == l.DefaultLang { // don't check default, other than for valid syntax
+		if l.ICU {
+			for _, s := range keys {
+				if _, err := parseICU(values[s].Value); err != nil {
+					l.report(lang, s, "", RuleICUSyntax, err)
+				}
+			}
+		}
 		return nil
 	}
 
-	for s, d := range data[lang] {
+	for _, s := range keys {
+		d := values[s]
 		if s != d.Name {
-			Logger.Error().Msgf("%s: '%s'\tfatally incorrect", lang, s)
+			l.report(lang, s, "", RuleKeyMismatch, fmt.Errorf("fatally incorrect; stored as '%s'", d.Name))
 			continue
 		}
-		defLangVal := data[l.DefaultLang][s]
+		defLangVal, _ := cat.Lookup(l.DefaultLang, s)
 
 		if defLangVal.Id != d.Id {
-			Logger.Error().Msgf("%s: '%s'\thas different ids from default language %s", lang, s, l.DefaultLang)
+			l.report(lang, s, "", RuleIdMismatch, fmt.Errorf("has id %d, but %d in default language %s", d.Id, defLangVal.Id, l.DefaultLang))
+			continue
+		}
+
+		if len(defLangVal.Plurals) > 0 {
+			l.checkPlurals(v, lang, s, defLangVal, d)
 			continue
 		}
 
-		if defLangVal.Value == d.Value {
-			// Same; skip.
+		if defLangVal.Select != nil {
+			if err := checkSelect(defLangVal.Select, d.Select); err != nil {
+				l.report(lang, s, "", RuleSelect, err)
+			}
 			continue
 		}
 
-		if err := checkCurlies(defLangVal.Value, d.Value); err != nil {
-			Logger.Error().Msgf("%s: '%s'\tcurlies mismatch: %s", lang, s, err.Error())
+		if defLangVal.Value == d.Value || d.Value == "" {
+			// Same, or not translated yet; skip.
+			continue
+		}
 
+		if l.ICU {
+			if err := checkICU(defLangVal.Value, d.Value); err != nil {
+				l.report(lang, s, "", RuleICUArgs, err)
+			}
+		} else if err := checkCurlies(defLangVal.Value, d.Value); err != nil {
+			l.report(lang, s, "", RuleCurlies, err)
 		}
 		if err := checkValidHTML(v, defLangVal.Value, d.Value); err != nil {
-			Logger.Error().Msgf("%s: '%s'\tHTML error: %s", lang, s, err.Error())
+			l.report(lang, s, "", RuleHTML, err)
 		}
 		// if err := checkWS(defLangVal.Value, d.Value); err != nil {
 		//	Logger.Error().Msgf("%s: '%s'\twhitespace error: %s", lang, s, err.Error())
 		// }
 		if err := checkForSymbols(defLangVal.Value, d.Value); err != nil {
-			Logger.Error().Msgf("%s: '%s'\tsymbols error: %s", lang, s, err.Error())
+			l.report(lang, s, "", RuleSymbols, err)
 		}
 	}
 	// TODO: investigate changing decoder
 	return nil
 }
 
+func (l *Locer) checkPlurals(v htmlcheck.Validator, lang string, s string, defLangVal Value, d Value) {
+	for _, p := range d.Plurals {
+		// compare against the default's matching form; languages with more forms than the default fall back to "other".
+		def := defLangVal.Plural(p.Form)
+		if def == "" {
+			def = defLangVal.Plural("other")
+		}
+		if p.Value == "" || p.Value == def {
+			continue
+		}
+		if err := checkCurlies(def, p.Value); err != nil && p.Form != "one" {
+			// the "one" form commonly drops the count; only other forms need every tag.
+			l.report(lang, s, p.Form, RuleCurlies, err)
+		}
+		if err := checkValidHTML(v, def, p.Value); err != nil {
+			l.report(lang, s, p.Form, RuleHTML, err)
+		}
+	}
+}
+
 func checkValidHTML(v htmlcheck.Validator, def string, custom string) error {
 	errs := v.ValidateHtmlString(custom)
 	if len(errs) == 0 {
@@ -751,7 +1052,7 @@ func checkForSymbols(def string, custom string) error {
 	return nil
 }
 
-var curliesRex = regexp.MustCompile(`\{\d+?\}`)
+var curliesRex = regexp.MustCompile(`\{\d+(?:\s*,[^{}]*)?\}`)
 
 // Basic regex check to see if expected matches. Should be good enough for now.
 func checkCurlies(def string, custom string) error {
@@ -791,12 +1092,29 @@ func checkCurlies(def string, custom string) error {
 	return nil
 }
 
-func (l *Locer) getUnFmtFunc(name string) string {
+// getUnPluralFunc returns the name of the non-plural version of the plural func called name, configured as entry.
+func (l *Locer) getUnPluralFunc(entry string, name string) string {
+	if !strings.HasSuffix(name, "p") {
+		Logger.Warn().Msgf("plural func %s has no matching non-plural func to call; fix the call manually", name)
+		return name
+	}
+	base := name[:len(name)-1]
+	entryBase := strings.TrimSuffix(entry, "p")
+	if inFuncs(l.Funcs, base) || inFuncs(l.Fmtfuncs, base) || inFuncs(l.Funcs, entryBase) || inFuncs(l.Fmtfuncs, entryBase) {
+		// found simple func; return.
+		return base
+	}
+	Logger.Warn().Msgf("plural func %s has no matching non-plural func to call; fix the call manually", name)
+	return name
+}
+
+// getUnFmtFunc returns the name of the non-formatting version of the func called name, configured as entry.
+func (l *Locer) getUnFmtFunc(entry string, name string) string {
 	if !strings.HasSuffix(name, "f") {
 		// not a formatting function; all ok.
 		return name
 	}
-	if _, ok := l.Fmtfuncs[name[:len(name)-1]]; ok {
+	if inFuncs(l.Fmtfuncs, name[:len(name)-1]) || inFuncs(l.Fmtfuncs, strings.TrimSuffix(entry, "f")) {
 		// found simple func; return.
 		return name[:len(name)-1]
 	}
@@ -0,0 +1,77 @@
+package loc
+
+import (
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+type langMatcher struct {
+	langs   []string // default language first, then Languages()
+	tags    []language.Tag
+	names   []string // the language each of tags was parsed from, as the catalog names it
+	matcher language.Matcher
+}
+
+// Match returns the loaded language which best fits the given user preferences, along with the confidence of the
+// match. The language is returned as the catalog names it, eg after its directory, so it can be passed to Trnl. Each pref may be an Accept-Language header ("da, en-GB;q=0.8") or a single locale such as a client's
+// language code ("pt-br"). If nothing matches, the default language is returned with language.No confidence, so
+// callers can decide whether to fall back or ask the user.
+func (c *Catalog) Match(prefs ...string) (string, language.Confidence) {
+	m := c.langMatcher()
+	if len(m.tags) == 0 {
+		return c.DefaultLang(), language.No
+	}
+
+	var want []language.Tag
+	for _, p := range prefs {
+		tags, _, err := language.ParseAcceptLanguage(p)
+		if err != nil {
+			Logger.Debug().Err(err).Msgf("ignoring invalid language preference '%s'", p)
+			continue
+		}
+		want = append(want, tags...)
+	}
+
+	_, idx, conf := m.matcher.Match(want...)
+	return m.names[idx], conf
+}
+
+func (c *Catalog) langMatcher() *langMatcher {
+	langs := append([]string{c.DefaultLang()}, c.Languages()...)
+
+	c.mu.RLock()
+	m := c.matcher
+	c.mu.RUnlock()
+	if m != nil && slices.Equal(m.langs, langs) {
+		return m
+	}
+
+	m = &langMatcher{langs: langs}
+	seen := make(map[language.Tag]struct{})
+	for _, l := range langs {
+		tag, err := language.Parse(l)
+		if err != nil {
+			Logger.Debug().Err(err).Msgf("language '%s' is not a valid BCP 47 tag; it cannot be matched", l)
+			continue
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		m.tags = append(m.tags, tag)
+		m.names = append(m.names, l)
+	}
+	if len(m.tags) > 0 {
+		m.matcher = language.NewMatcher(m.tags)
+	}
+
+	c.mu.Lock()
+	c.matcher = m
+	c.mu.Unlock()
+	return m
+}
+
+func Match(prefs ...string) (string, language.Confidence) {
+	return pkgCatalog().Match(prefs...)
+}
@@ -0,0 +1,144 @@
+package loc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// isPattern reports whether a Handle argument is a package pattern, such as ./... or an import path, rather than a
+// file or directory. Arguments which are neither are left to fail as missing paths.
+func (l *Locer) isPattern(arg string) bool {
+	if strings.Contains(arg, "...") {
+		return true
+	}
+	if _, err := os.Stat(arg); !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if strings.HasSuffix(arg, ".go") || filepath.IsAbs(arg) || strings.HasPrefix(arg, ".") {
+		return false
+	}
+	return l.isImportPath(arg)
+}
+
+// isImportPath reports whether arg is the import path of a package the go command finds.
+func (l *Locer) isImportPath(arg string) bool {
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles}
+	cfg.BuildFlags, cfg.Env = l.buildFlags()
+	pkgs, err := packages.Load(cfg, arg)
+	return err == nil && len(pkgs) == 1 && len(pkgs[0].Errors) == 0
+}
+
+// missingArg returns the error of a Handle argument which is neither a file, a directory nor a package.
+func missingArg(arg string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s is not a file, directory or package", arg)
+	}
+	return err
+}
+
+// loadPatterns lists the Go files of the packages matching patterns. It follows the go command: module and
+// workspace boundaries are honoured, and vendor and testdata directories are never matched by a wildcard.
+func (l *Locer) loadPatterns(patterns []string) ([]string, error) {
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles, Tests: l.Tests}
+	cfg.BuildFlags, cfg.Env = l.buildFlags()
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, pkg := range pkgs {
+		for _, pkgErr := range pkg.Errors {
+			Logger.Warn().Msgf("%s: %s", pkg.PkgPath, pkgErr)
+		}
+		files = append(files, pkg.GoFiles...)
+	}
+	return files, nil
+}
+
+// handlePackages loads the packages in args with type information, and calls hdnl on each of their files.
+func (l *Locer) handlePackages(args []string, hdnl func(*ast.File)) error {
+	var patterns []string
+	for _, arg := range args {
+		if l.isPattern(arg) {
+			patterns = append(patterns, arg)
+			continue
+		}
+		fi, err := os.Stat(arg)
+		if err != nil {
+			return missingArg(arg, err)
+		}
+		if !fi.IsDir() {
+			patterns = append(patterns, "file="+arg)
+		} else if filepath.IsAbs(arg) {
+			patterns = append(patterns, arg)
+		} else {
+			patterns = append(patterns, "./"+filepath.Clean(arg))
+		}
+	}
+
+	cfg := &packages.Config{
+		// dependencies are type checked from source too, so loading doesn't rely on the export data of the
+		// installed toolchain.
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes |
+			packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps,
+		Fset:  l.Fset,
+		Tests: l.Tests,
+	}
+	cfg.BuildFlags, cfg.Env = l.buildFlags()
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return err
+	}
+	defer func() { l.info = nil }()
+	for _, pkg := range pkgs {
+		for _, pkgErr := range pkg.Errors {
+			Logger.Warn().Msgf("%s: %s", pkg.PkgPath, pkgErr)
+		}
+		l.info = pkg.TypesInfo
+		for _, f := range pkg.Syntax {
+			name := moduleName(l.Fset.File(f.Pos()).Name())
+			if reason := l.skipReason(name); reason != "" {
+				l.skip(name, reason)
+				continue
+			}
+			l.handleFile(name, f, hdnl)
+		}
+	}
+	return nil
+}
+
+// handleFile calls hdnl on f, unless it has been handled already or is generated code.
+func (l *Locer) handleFile(name string, f *ast.File, hdnl func(*ast.File)) {
+	if _, ok := l.Checked[name]; ok {
+		return // todo: check for file name clashes in diff packages?
+	}
+	if !l.Generated && ast.IsGenerated(f) {
+		l.skip(name, "generated")
+		return
+	}
+	l.Checked[name] = struct{}{}
+	hdnl(f)
+}
+
+// moduleName returns the name of the module for a source file. Modules are named after files as passed on the
+// command line, which is usually relative to the current directory.
+func moduleName(filename string) string {
+	if !filepath.IsAbs(filename) {
+		return filename
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return filename
+	}
+	if rel, err := filepath.Rel(wd, filename); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return filename
+}
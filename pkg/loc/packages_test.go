@@ -0,0 +1,73 @@
+package loc
+
+import (
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHandlePackagesPatterns(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"go.mod":                        "module example.com/app\n\ngo 1.22\n",
+		"main.go":                       "package main\n\nfunc main() {}\n",
+		"sub/sub.go":                    "package sub\n",
+		"sub/gen.go":                    "// Code generated by stringer; DO NOT EDIT.\n\npackage sub\n",
+		"testdata/td.go":                "package td\n",
+		"vendor/modules.txt":            "",
+		"vendor/example.com/dep/dep.go": "package dep\n",
+		"nested/go.mod":                 "module example.com/nested\n\ngo 1.22\n",
+		"nested/nested.go":              "package nested\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	l := &Locer{Checked: make(map[string]struct{}), Fset: token.NewFileSet()}
+	var got []string
+	if err := l.Handle([]string{"./..."}, func(f *ast.File) {
+		got = append(got, filepath.ToSlash(l.Fset.File(f.Pos()).Name()))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if want := []string{"main.go", "sub/sub.go"}; !slices.Equal(got, want) {
+		t.Errorf("handled %v, want %v", got, want)
+	}
+
+	// import paths are patterns; mistyped paths are errors rather than matching nothing.
+	got = nil
+	l = &Locer{Checked: make(map[string]struct{}), Fset: token.NewFileSet()}
+	if err := l.Handle([]string{"example.com/app/sub"}, func(f *ast.File) {
+		got = append(got, filepath.ToSlash(l.Fset.File(f.Pos()).Name()))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sub/sub.go"}; !slices.Equal(got, want) {
+		t.Errorf("handled %v for an import path, want %v", got, want)
+	}
+	for _, arg := range []string{"sbu", "sub/missing.go", "example.com/app/nope"} {
+		err := l.Handle([]string{arg}, func(*ast.File) {})
+		if err == nil || !strings.Contains(err.Error(), "not a file, directory or package") {
+			t.Errorf("Handle(%s) error = %v, want a missing path error", arg, err)
+		}
+	}
+}
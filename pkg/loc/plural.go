@@ -0,0 +1,149 @@
+package loc
+
+import (
+	"fmt"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// Plural is a single CLDR plural form of a Value, such as "one" or "few".
+type Plural struct {
+	Form  string `xml:"form,attr" json:"form" yaml:"form"`
+	Value string `xml:",chardata" json:"value" yaml:"value"`
+}
+
+// pluralFormNames lists the CLDR plural categories, in CLDR order.
+var pluralFormNames = []struct {
+	form plural.Form
+	name string
+}{
+	{plural.Zero, "zero"},
+	{plural.One, "one"},
+	{plural.Two, "two"},
+	{plural.Few, "few"},
+	{plural.Many, "many"},
+	{plural.Other, "other"},
+}
+
+func pluralFormName(f plural.Form) string {
+	for _, p := range pluralFormNames {
+		if p.form == f {
+			return p.name
+		}
+	}
+	return "other"
+}
+
+// pluralForm returns the CLDR plural category lang uses for the integer n.
+func pluralForm(lang string, n int) string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+	return countForm(plural.Cardinal, tag, n)
+}
+
+// countForm returns the plural category of the integer n in tag under rules, such as plural.Cardinal.
+func countForm(rules *plural.Rules, tag language.Tag, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	return pluralFormName(rules.MatchPlural(tag, n, 0, 0, 0, 0))
+}
+
+// pluralForms returns every plural category lang needs for integer counts, in CLDR order.
+func pluralForms(lang string) []string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return []string{"one", "other"}
+	}
+	found := map[plural.Form]bool{plural.Other: true}
+	for _, n := range append(rangeInts(0, 200), 1000000) {
+		found[plural.Cardinal.MatchPlural(tag, n, 0, 0, 0, 0)] = true
+	}
+	var out []string
+	for _, p := range pluralFormNames {
+		if found[p.form] {
+			out = append(out, p.name)
+		}
+	}
+	return out
+}
+
+func rangeInts(from, to int) []int {
+	out := make([]int, 0, to-from)
+	for i := from; i < to; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+// Plural returns the text of the given plural form, or an empty string if it isn't set.
+func (v Value) Plural(form string) string {
+	for _, p := range v.Plurals {
+		if p.Form == form {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// emptyPlurals returns the plural forms lang needs, with no text set.
+func emptyPlurals(lang string) []Plural {
+	var out []Plural
+	for _, f := range pluralForms(lang) {
+		out = append(out, Plural{Form: f})
+	}
+	return out
+}
+
+// Trnp returns the plural form of trnlVal matching the count n, in the first language of lang's FallbackChain
+// which has that form translated.
+func (c *Catalog) Trnp(lang string, trnlVal string, n int) string {
+	return c.resolve(lang, trnlVal, func(l string, v Value) string {
+		return v.pluralText(l, n)
+	})
+}
+
+// Trnpf is Trnp with placeholders replaced and formatted from dataMap, as in Trnlf.
+func (c *Catalog) Trnpf(lang string, trnlVal string, n int, dataMap map[string]string) string {
+	if c.icuEnabled() {
+		return c.resolve(lang, trnlVal, func(l string, v Value) string {
+			return c.renderICU(l, trnlVal, v.pluralText(l, n), dataMap)
+		})
+	}
+
+	return replacePlaceholders(c.resolve(lang, trnlVal, func(l string, v Value) string {
+		return formatArgs(l, v.pluralText(l, n), dataMap)
+	}), dataMap)
+}
+
+// pluralText returns the text of v for the count n in lang; values without plurals return their plain text.
+func (v Value) pluralText(lang string, n int) string {
+	if len(v.Plurals) == 0 {
+		return v.text(nil)
+	}
+	return v.Plural(pluralForm(lang, n))
+}
+
+func Trnp(lang string, trnlVal string, n int) string {
+	return pkgCatalog().Trnp(lang, trnlVal, n)
+}
+
+func Trnpf(lang string, trnlVal string, n int, dataMap map[string]string) string {
+	return pkgCatalog().Trnpf(lang, trnlVal, n, dataMap)
+}
+
+// Addp marks a plural string for extraction. Until extracted, it formats one or other with n followed by format.
+func Addp(one string, other string, n int, format ...interface{}) string {
+	Logger.Warn().Msg("unloaded translation string for Addp()")
+	text := other
+	if n == 1 {
+		text = one
+	}
+	return fmt.Sprintf(text, append([]interface{}{n}, format...)...)
+}
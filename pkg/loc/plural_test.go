@@ -0,0 +1,78 @@
+package loc
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestPluralForms(t *testing.T) {
+	tests := map[string][]string{
+		"en-GB": {"one", "other"},
+		"pl":    {"one", "few", "many", "other"},
+		"ja":    {"other"},
+		"??":    {"one", "other"},
+	}
+	for lang, want := range tests {
+		if got := pluralForms(lang); !slices.Equal(got, want) {
+			t.Errorf("pluralForms(%s) = %v, want %v", lang, got, want)
+		}
+	}
+}
+
+func TestPluralForm(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 1, "one"},
+		{"en", -1, "one"},
+		{"en", 10000001, "other"},
+		{"en", 20000001, "other"},
+		{"fr", 1, "one"},
+		{"fr", 10000000, "other"},
+		{"pt", 20000000, "other"},
+		{"ru", 10000001, "one"},
+		{"ru", 10000011, "many"},
+		{"pl", 10000001, "many"},
+	}
+	for _, tc := range tests {
+		if got := pluralForm(tc.lang, tc.n); got != tc.want {
+			t.Errorf("pluralForm(%s, %d) = %q, want %q", tc.lang, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestCatalogTrnp(t *testing.T) {
+	c := NewCatalog("en-US")
+	c.add("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Plurals: []Plural{
+		{Form: "one", Value: "{1} file"},
+		{Form: "other", Value: "{1} files"},
+	}}}})
+	c.add("pl", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Plurals: []Plural{
+		{Form: "one", Value: "{1} plik"},
+		{Form: "few", Value: "{1} pliki"},
+		{Form: "many", Value: ""},
+		{Form: "other", Value: "{1} pliku"},
+	}}}})
+
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{lang: "en-US", n: 1, want: "1 file"},
+		{lang: "en-US", n: 5, want: "5 files"},
+		{lang: "pl", n: 1, want: "1 plik"},
+		{lang: "pl", n: 3, want: "3 pliki"},
+		{lang: "pl", n: 5, want: "5 files"}, // untranslated "many" falls back to the default language
+		{lang: "fr", n: 0, want: "0 files"},
+	}
+	for _, tc := range tests {
+		got := c.Trnpf(tc.lang, "mod.go:1", tc.n, map[string]string{"1": strconv.Itoa(tc.n)})
+		if got != tc.want {
+			t.Errorf("Trnpf(%s, %d) = %q, want %q", tc.lang, tc.n, got, tc.want)
+		}
+	}
+}
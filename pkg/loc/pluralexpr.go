@@ -0,0 +1,345 @@
+package loc
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gettextPluralExprs are the usual gettext plural expressions, with msgstr indexes in CLDR form order. The first
+// one which agrees with the CLDR rules of a language is used for its Plural-Forms header.
+var gettextPluralExprs = []string{
+	"0",
+	"(n != 1)",
+	"(n > 1)",
+	"(n%10 != 1 || n%100 == 11)",
+	"(n%10 != 1)",
+	"(n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2)",
+	"(n == 1 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2)",
+	"(n == 1 ? 0 : n >= 2 && n <= 4 ? 1 : 2)",
+	"(n%10 == 1 && (n%100 < 11 || n%100 > 19) ? 0 : n%10 >= 2 && (n%100 < 11 || n%100 > 19) ? 1 : 2)",
+	"(n%10 == 0 || n%100 >= 11 && n%100 <= 19 ? 0 : n%10 == 1 && n%100 != 11 ? 1 : 2)",
+	"(n == 1 ? 0 : n == 0 || n%100 >= 1 && n%100 <= 19 ? 1 : 2)",
+	"(n%100 == 1 ? 0 : n%100 == 2 ? 1 : n%100 == 3 || n%100 == 4 ? 2 : 3)",
+	"(n == 1 ? 0 : n == 2 ? 1 : n > 10 && n%10 == 0 ? 2 : 3)",
+	"(n == 1 ? 0 : n == 0 || n%100 >= 2 && n%100 <= 10 ? 1 : n%100 >= 11 && n%100 <= 19 ? 2 : 3)",
+	"(n == 1 || n == 11 ? 0 : n == 2 || n == 12 ? 1 : n >= 3 && n <= 19 ? 2 : 3)",
+	"(n == 1 ? 0 : n == 2 ? 1 : n >= 3 && n <= 6 ? 2 : n >= 7 && n <= 10 ? 3 : 4)",
+	"(n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n%100 >= 3 && n%100 <= 10 ? 3 : n%100 >= 11 ? 4 : 5)",
+	"(n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n == 3 ? 3 : n == 6 ? 4 : 5)",
+}
+
+// pluralSamples are the counts a plural expression is checked against CLDR with.
+var pluralSamples = append(rangeInts(0, 1000), 1000000)
+
+// poPluralForms returns the gettext Plural-Forms header of lang, whose msgstr indexes follow forms.
+func poPluralForms(lang string, forms []string) string {
+	for _, expr := range gettextPluralExprs {
+		if f, err := parsePluralExpr(expr); err == nil && pluralExprMatches(f, lang, forms) {
+			return fmt.Sprintf("nplurals=%d; plural=%s;", len(forms), expr)
+		}
+	}
+	return fmt.Sprintf("nplurals=%d; plural=%s;", len(forms), derivePluralExpr(lang, forms))
+}
+
+func pluralExprMatches(f func(n int) int, lang string, forms []string) bool {
+	for _, n := range pluralSamples {
+		i := f(n)
+		if i < 0 || i >= len(forms) || forms[i] != pluralForm(lang, n) {
+			return false
+		}
+	}
+	return true
+}
+
+// derivePluralExpr builds a plural expression for a language none of gettextPluralExprs fit. Above 100, CLDR
+// integer rules only depend on the last two digits, so smaller counts are listed where they differ, as are
+// millions, which some languages treat apart.
+func derivePluralExpr(lang string, forms []string) string {
+	index := func(n int) int {
+		for i, f := range forms {
+			if f == pluralForm(lang, n) {
+				return i
+			}
+		}
+		return len(forms) - 1
+	}
+
+	var conds []string
+	if i := index(1000000); i != index(100) {
+		conds = append(conds, fmt.Sprintf("n != 0 && n%%1000000 == 0 ? %d", i))
+	}
+	for n := 0; n < 100; n++ {
+		if i := index(n); i != index(n+100) {
+			conds = append(conds, fmt.Sprintf("n == %d ? %d", n, i))
+		}
+	}
+	counts := make(map[int]int)
+	for m := 0; m < 100; m++ {
+		counts[index(100+m)]++
+	}
+	def := len(forms) - 1
+	for i, c := range counts {
+		if c > counts[def] || c == counts[def] && i > def {
+			def = i
+		}
+	}
+	byIndex := make(map[int][]string)
+	for m := 0; m < 100; m++ {
+		if i := index(100 + m); i != def {
+			byIndex[i] = append(byIndex[i], fmt.Sprintf("n%%100 == %d", m))
+		}
+	}
+	indexes := make([]int, 0, len(byIndex))
+	for i := range byIndex {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		conds = append(conds, fmt.Sprintf("%s ? %d", strings.Join(byIndex[i], " || "), i))
+	}
+	if len(conds) == 0 {
+		return strconv.Itoa(def)
+	}
+	return "(" + strings.Join(conds, " : ") + " : " + strconv.Itoa(def) + ")"
+}
+
+// poFormsOrder returns the CLDR plural form of each msgstr index of a PO file for lang, read from its Plural-Forms
+// header. Files without a usable header are assumed to follow CLDR order.
+func poFormsOrder(lang string, header string) []string {
+	forms := pluralForms(lang)
+	nplurals, f, err := parsePluralFormsHeader(header)
+	if err != nil {
+		if header != "" {
+			Logger.Warn().Msgf("%s: ignoring Plural-Forms header: %s", lang, err)
+		}
+		return forms
+	}
+	order := make([]string, nplurals)
+	for _, n := range pluralSamples {
+		if i := f(n); i >= 0 && i < nplurals && order[i] == "" {
+			order[i] = pluralForm(lang, n)
+		}
+	}
+	// indexes no integer count reaches, such as "other" in Russian, keep their CLDR position.
+	for i := range order {
+		if order[i] == "" && i < len(forms) {
+			order[i] = forms[i]
+		}
+	}
+	return order
+}
+
+// parsePluralFormsHeader parses a gettext Plural-Forms header, such as "nplurals=2; plural=(n != 1);".
+func parsePluralFormsHeader(header string) (int, func(n int) int, error) {
+	var nplurals int
+	var f func(n int) int
+	for _, field := range strings.Split(header, ";") {
+		k, v, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch strings.TrimSpace(k) {
+		case "nplurals":
+			nplurals, err = strconv.Atoi(strings.TrimSpace(v))
+		case "plural":
+			f, err = parsePluralExpr(v)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	if nplurals < 1 || f == nil {
+		return 0, nil, fmt.Errorf("invalid Plural-Forms %q", header)
+	}
+	return nplurals, f, nil
+}
+
+// parsePluralExpr parses the C expression of a gettext plural rule.
+func parsePluralExpr(s string) (func(n int) int, error) {
+	p := &pluralParser{}
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			p.toks = append(p.toks, s[i:j])
+			i = j
+		case i+1 < len(s) && slices.Contains([]string{"==", "!=", "<=", ">=", "&&", "||"}, s[i:i+2]):
+			p.toks = append(p.toks, s[i:i+2])
+			i += 2
+		case strings.IndexByte("n<>!%*/+-?:()", c) >= 0:
+			p.toks = append(p.toks, string(c))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q in plural expression %q", c, s)
+		}
+	}
+	f, err := p.ternary()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("plural expression %q: %w", strings.TrimSpace(s), err)
+	}
+	return f, nil
+}
+
+type pluralParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *pluralParser) accept(tok string) bool {
+	if p.pos < len(p.toks) && p.toks[p.pos] == tok {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *pluralParser) ternary() (func(n int) int, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	a, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept(":") {
+		return nil, fmt.Errorf("missing ':'")
+	}
+	b, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return func(n int) int {
+		if cond(n) != 0 {
+			return a(n)
+		}
+		return b(n)
+	}, nil
+}
+
+// pluralOps lists the binary operators by increasing precedence.
+var pluralOps = [][]string{{"||"}, {"&&"}, {"==", "!="}, {"<", ">", "<=", ">="}, {"+", "-"}, {"*", "/", "%"}}
+
+func (p *pluralParser) binary(level int) (func(n int) int, error) {
+	if level == len(pluralOps) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, o := range pluralOps[level] {
+			if p.accept(o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = pluralBinary(op, x, y)
+	}
+}
+
+func pluralBinary(op string, x, y func(n int) int) func(n int) int {
+	return func(n int) int {
+		a, b := x(n), y(n)
+		var r bool
+		switch op {
+		case "||":
+			r = a != 0 || b != 0
+		case "&&":
+			r = a != 0 && b != 0
+		case "==":
+			r = a == b
+		case "!=":
+			r = a != b
+		case "<":
+			r = a < b
+		case ">":
+			r = a > b
+		case "<=":
+			r = a <= b
+		case ">=":
+			r = a >= b
+		case "+":
+			return a + b
+		case "-":
+			return a - b
+		case "*":
+			return a * b
+		case "/", "%":
+			if b == 0 {
+				return 0
+			}
+			if op == "/" {
+				return a / b
+			}
+			return a % b
+		}
+		if r {
+			return 1
+		}
+		return 0
+	}
+}
+
+func (p *pluralParser) unary() (func(n int) int, error) {
+	switch {
+	case p.accept("!"):
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n int) int {
+			if x(n) == 0 {
+				return 1
+			}
+			return 0
+		}, nil
+	case p.accept("-"):
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(n int) int { return -x(n) }, nil
+	case p.accept("n"):
+		return func(n int) int { return n }, nil
+	case p.accept("("):
+		x, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return x, nil
+	}
+	if p.pos == len(p.toks) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	v, err := strconv.Atoi(p.toks[p.pos])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %q", p.toks[p.pos])
+	}
+	p.pos++
+	return func(int) int { return v }, nil
+}
@@ -0,0 +1,357 @@
+package loc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// poTemplate is the name of the exported POT file; each language is exported next to it as <lang>.po.
+const poTemplate = "messages.pot"
+
+// poEntry is a single message of a gettext PO file.
+type poEntry struct {
+	comments  []string // translator comments, "# "
+	extracted []string // extracted comments, "#."
+	refs      []string // source references, "#:"
+	flags     []string // "#,", eg fuzzy
+	ctxt      string
+	id        string
+	idPlural  string
+	str       []string // msgstr, or msgstr[n] when idPlural is set
+}
+
+type poFile struct {
+	header  [][2]string
+	entries []poEntry
+}
+
+func (p *poFile) get(key string) string {
+	for _, h := range p.header {
+		if h[0] == key {
+			return h[1]
+		}
+	}
+	return ""
+}
+
+func (e poEntry) fuzzy() bool {
+	for _, f := range e.flags {
+		if f == "fuzzy" {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Locer) exportPO(sets []moduleSet, langs []string, outDir string) error {
+	if err := writePOFile(filepath.Join(outDir, poTemplate), l.buildPO(sets, "")); err != nil {
+		return err
+	}
+	for _, lang := range langs {
+		if err := writePOFile(filepath.Join(outDir, lang+".po"), l.buildPO(sets, lang)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildPO builds the PO file of lang, or the POT template of the default language if lang is empty.
+func (l *Locer) buildPO(sets []moduleSet, lang string) *poFile {
+	forms := pluralForms(l.DefaultLang)
+	formsLang := l.DefaultLang
+	p := &poFile{header: [][2]string{
+		{"Content-Type", "text/plain; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "8bit"},
+	}}
+	if lang != "" {
+		forms = pluralForms(lang)
+		formsLang = lang
+		p.header = append(p.header, [2]string{"Language", lang})
+	}
+	p.header = append(p.header,
+		[2]string{"Plural-Forms", poPluralForms(formsLang, forms)},
+		[2]string{"X-Generator", "goloc"},
+	)
+
+	for _, set := range sets {
+		var rows []Value
+		if lang != "" {
+			rows = set.rows(lang)
+		}
+		for i, def := range set.def.Rows {
+			if def.Name == "" {
+				continue
+			}
+			var cur Value
+			if rows != nil {
+				cur = rows[i]
+			}
+			base := poEntry{refs: []string{sourceModule(def.Name)}}
+			if def.Comment != "" && def.Comment != def.Name {
+				base.extracted = []string{def.Comment}
+			}
+			if c := translatorComment(lang, def, cur); lang != "" && c != "" {
+				base.comments = strings.Split(c, "\n")
+			}
+
+			switch {
+			case len(def.Plurals) > 0:
+				e := base
+				e.ctxt = def.Name
+				e.id = def.Plural("one")
+				e.idPlural = def.Plural("other")
+				for _, f := range forms {
+					e.str = append(e.str, cur.Plural(f))
+				}
+				p.entries = append(p.entries, e)
+			case def.Select != nil:
+				for _, c := range def.Select.Cases {
+					e := base
+					e.ctxt = unitID(def.Name, c.Key)
+					e.extracted = append(e.extracted, fmt.Sprintf("select on '%s', case '%s'", def.Select.Arg, c.Key))
+					e.id = c.Value
+					e.str = []string{""}
+					if cur.Select != nil {
+						e.str[0] = cur.Select.Case(c.Key)
+					}
+					p.entries = append(p.entries, e)
+				}
+			default:
+				e := base
+				e.ctxt = def.Name
+				e.id = def.Value
+				e.str = []string{cur.Value}
+				p.entries = append(p.entries, e)
+			}
+		}
+	}
+	return p
+}
+
+// importPO merges the translations of a PO file into its language. Untranslated and fuzzy messages are skipped.
+func (l *Locer) importPO(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer ioClose(f)
+	p, err := readPO(f)
+	if err != nil {
+		return err
+	}
+
+	lang := p.get("Language")
+	if lang == "" {
+		lang = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	forms := poFormsOrder(lang, p.get("Plural-Forms"))
+
+	imported := make(map[string]importedUnit)
+	for _, e := range p.entries {
+		if e.fuzzy() || len(e.str) == 0 {
+			Logger.Debug().Msgf("skipping fuzzy or empty translation of %s", e.ctxt)
+			continue
+		}
+		comment := strings.Join(e.comments, "\n")
+		if e.idPlural == "" {
+			imported[e.ctxt] = importedUnit{text: e.str[0], state: StateTranslated, comment: comment}
+			continue
+		}
+		for i, s := range e.str {
+			if i < len(forms) {
+				imported[unitID(e.ctxt, forms[i])] = importedUnit{text: s, state: StateTranslated, comment: comment}
+			}
+		}
+	}
+	return l.mergeUnits(file, lang, imported)
+}
+
+func writePOFile(filename string, p *poFile) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer ioClose(f)
+	w := bufio.NewWriter(f)
+	writePO(w, p)
+	return w.Flush()
+}
+
+func writePO(w io.Writer, p *poFile) {
+	var header strings.Builder
+	for _, h := range p.header {
+		header.WriteString(h[0] + ": " + h[1] + "\n")
+	}
+	writePOString(w, "msgid", "")
+	writePOString(w, "msgstr", header.String())
+
+	for _, e := range p.entries {
+		fmt.Fprintln(w)
+		for _, c := range e.comments {
+			fmt.Fprintln(w, strings.TrimRight("# "+c, " "))
+		}
+		for _, c := range e.extracted {
+			fmt.Fprintln(w, "#. "+c)
+		}
+		if len(e.refs) > 0 {
+			fmt.Fprintln(w, "#: "+strings.Join(e.refs, " "))
+		}
+		if len(e.flags) > 0 {
+			fmt.Fprintln(w, "#, "+strings.Join(e.flags, ", "))
+		}
+		if e.ctxt != "" {
+			writePOString(w, "msgctxt", e.ctxt)
+		}
+		writePOString(w, "msgid", e.id)
+		if e.idPlural == "" {
+			str := ""
+			if len(e.str) > 0 {
+				str = e.str[0]
+			}
+			writePOString(w, "msgstr", str)
+			continue
+		}
+		writePOString(w, "msgid_plural", e.idPlural)
+		for i, s := range e.str {
+			writePOString(w, "msgstr["+strconv.Itoa(i)+"]", s)
+		}
+	}
+}
+
+// writePOString writes a keyword and its quoted string, split after each newline as gettext tools do.
+func writePOString(w io.Writer, keyword string, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		fmt.Fprintf(w, "%s %s\n", keyword, quotePO(s))
+		return
+	}
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		fmt.Fprintln(w, quotePO(line))
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func quotePO(s string) string {
+	return `"` + poEscaper.Replace(s) + `"`
+}
+
+// readPO parses a PO file. Obsolete (#~) messages and previous strings (#|) are ignored.
+func readPO(r io.Reader) (*poFile, error) {
+	p := &poFile{}
+	var (
+		e       poEntry
+		started bool    // e has content
+		hasStr  bool    // e has a msgstr; another message starts at the next keyword
+		field   *string // string continued by following quoted lines
+	)
+	flush := func() {
+		if !started {
+			return
+		}
+		if e.ctxt == "" && e.id == "" && len(p.entries) == 0 && p.header == nil {
+			for _, line := range strings.Split(strings.Join(e.str, ""), "\n") {
+				if k, v, ok := strings.Cut(line, ":"); ok {
+					p.header = append(p.header, [2]string{strings.TrimSpace(k), strings.TrimSpace(v)})
+				}
+			}
+		} else {
+			p.entries = append(p.entries, e)
+		}
+		e = poEntry{}
+		started, hasStr, field = false, false, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, "#~"), strings.HasPrefix(line, "#|"):
+			continue
+		case strings.HasPrefix(line, "#"):
+			if hasStr {
+				flush()
+			}
+			started = true
+			field = nil
+			switch {
+			case strings.HasPrefix(line, "#."):
+				e.extracted = append(e.extracted, strings.TrimSpace(line[2:]))
+			case strings.HasPrefix(line, "#:"):
+				e.refs = append(e.refs, strings.Fields(line[2:])...)
+			case strings.HasPrefix(line, "#,"):
+				for _, f := range strings.Split(line[2:], ",") {
+					e.flags = append(e.flags, strings.TrimSpace(f))
+				}
+			default:
+				e.comments = append(e.comments, strings.TrimPrefix(line[1:], " "))
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("line %d: string without a keyword", lineNo)
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid string %s", lineNo, line)
+			}
+			*field += s
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		s, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string %s", lineNo, rest)
+		}
+		if hasStr && (keyword == "msgctxt" || keyword == "msgid") {
+			flush()
+		}
+		started = true
+		switch {
+		case keyword == "msgctxt":
+			e.ctxt = s
+			field = &e.ctxt
+		case keyword == "msgid":
+			e.id = s
+			field = &e.id
+		case keyword == "msgid_plural":
+			e.idPlural = s
+			field = &e.idPlural
+		case keyword == "msgstr":
+			e.str = []string{s}
+			field = &e.str[0]
+			hasStr = true
+		case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+			n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+			if err != nil || n != len(e.str) {
+				return nil, fmt.Errorf("line %d: unexpected %s", lineNo, keyword)
+			}
+			e.str = append(e.str, s)
+			field = &e.str[n]
+			hasStr = true
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword '%s'", lineNo, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return p, nil
+}
@@ -0,0 +1,187 @@
+package loc
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTestTree(t *testing.T, dir string, files map[string]Translation) {
+	t.Helper()
+	for name, tr := range files {
+		if err := writeModuleFile(XMLFormat, filepath.Join(dir, filepath.FromSlash(name)), tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testTree() map[string]Translation {
+	return map[string]Translation{
+		"en-GB/bot/main.xml": {Counter: 3, Rows: []Value{
+			{Id: 1, Name: "bot/main.go:1", Value: "hello {1}", Comment: "bot/main.go:1"},
+			{Id: 2, Name: "bot/main.go:2", Plurals: []Plural{{Form: "one", Value: "{1} file"}, {Form: "other", Value: "{1} files"}}, Comment: "bot/main.go:2"},
+			{Id: 3, Name: "bot/main.go:3", Select: &Select{Arg: "g", Cases: []Case{{Key: "male", Value: "he"}, {Key: "other", Value: "they"}}}, Comment: "bot/main.go:3"},
+		}},
+		"de-DE/bot/main.xml": {Counter: 3, Rows: []Value{
+			{Id: 1, Name: "bot/main.go:1", Value: "hallo {1}", Comment: "hello {1}"},
+		}},
+	}
+}
+
+func TestExportPO(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, testTree())
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+	out := filepath.Join(dir, "export")
+	if err := l.Export("po", out); err != nil {
+		t.Fatal(err)
+	}
+
+	pot, err := os.ReadFile(filepath.Join(out, poTemplate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"#: bot/main.go\nmsgctxt \"bot/main.go:1\"\nmsgid \"hello {1}\"\nmsgstr \"\"\n",
+		"msgctxt \"bot/main.go:2\"\nmsgid \"{1} file\"\nmsgid_plural \"{1} files\"\nmsgstr[0] \"\"\nmsgstr[1] \"\"\n",
+		"msgctxt \"bot/main.go:3|male\"\nmsgid \"he\"\n",
+	} {
+		if !strings.Contains(string(pot), want) {
+			t.Errorf("POT is missing %q:\n%s", want, pot)
+		}
+	}
+
+	po, err := os.ReadFile(filepath.Join(out, "de-DE.po"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\"Language: de-DE\\n\"",
+		"\"Plural-Forms: nplurals=2; plural=(n != 1);\\n\"",
+		"msgid \"hello {1}\"\nmsgstr \"hallo {1}\"\n",
+	} {
+		if !strings.Contains(string(po), want) {
+			t.Errorf("PO is missing %q:\n%s", want, po)
+		}
+	}
+}
+
+func TestImportPO(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, testTree())
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir, Apply: true}
+
+	po := `msgid ""
+msgstr ""
+"Language: de-DE\n"
+"Plural-Forms: nplurals=2; plural=(n != 1);\n"
+
+# checked by Anna
+msgctxt "bot/main.go:2"
+msgid "{1} file"
+msgid_plural "{1} files"
+msgstr[0] "{1} Datei"
+msgstr[1] "{1} Dateien"
+
+msgctxt "bot/main.go:3|male"
+msgid "he"
+msgstr "er"
+
+#, fuzzy
+msgctxt "bot/main.go:3|other"
+msgid "they"
+msgstr "sie"
+
+msgctxt "bot/main.go:1"
+msgid "hello {1}"
+msgstr ""
+"guten Tag "
+"{1}"
+`
+	file := filepath.Join(dir, "de.po")
+	if err := os.WriteFile(file, []byte(po), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Import("", []string{file}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readModule(os.DirFS(dir), XMLFormat, "de-DE", "bot/main.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(got.Rows))
+	}
+	for i, row := range got.Rows {
+		if row.Id != i+1 {
+			t.Errorf("row %d has id %d, want %d", i, row.Id, i+1)
+		}
+	}
+	if got.Rows[0].Value != "guten Tag {1}" {
+		t.Errorf("value = %q, want %q", got.Rows[0].Value, "guten Tag {1}")
+	}
+	if p := got.Rows[1].Plural("other"); p != "{1} Dateien" {
+		t.Errorf("plural other = %q, want %q", p, "{1} Dateien")
+	}
+	if got.Rows[1].Comment != "checked by Anna" {
+		t.Errorf("comment = %q, want %q", got.Rows[1].Comment, "checked by Anna")
+	}
+	if c := got.Rows[2].Select.Case("male"); c != "er" {
+		t.Errorf("select male = %q, want %q", c, "er")
+	}
+	if c := got.Rows[2].Select.Case("other"); c != "" {
+		t.Errorf("fuzzy select other = %q, want it skipped", c)
+	}
+}
+
+func TestPOPluralForms(t *testing.T) {
+	for _, tc := range []struct{ lang, want string }{
+		{"ja", "nplurals=1; plural=0;"},
+		{"en-GB", "nplurals=2; plural=(n != 1);"},
+		{"fr", "nplurals=2; plural=(n > 1);"},
+		{"ru", "nplurals=4; plural=(n%10 == 1 && n%100 != 11 ? 0 : n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) ? 1 : 2);"},
+		{"cs", "nplurals=3; plural=(n == 1 ? 0 : n >= 2 && n <= 4 ? 1 : 2);"},
+		{"ar", "nplurals=6; plural=(n == 0 ? 0 : n == 1 ? 1 : n == 2 ? 2 : n%100 >= 3 && n%100 <= 10 ? 3 : n%100 >= 11 ? 4 : 5);"},
+	} {
+		if got := poPluralForms(tc.lang, pluralForms(tc.lang)); got != tc.want {
+			t.Errorf("poPluralForms(%s) = %q, want %q", tc.lang, got, tc.want)
+		}
+	}
+
+	// every header, including derived ones, must agree with CLDR.
+	for _, lang := range []string{"de", "pt", "is", "uk", "pl", "sk", "lt", "lv", "ro", "sl", "hr", "ga", "cy", "he", "mt", "gd", "br", "kw", "gv", "ksh", "shi", "mk", "be"} {
+		forms := pluralForms(lang)
+		header := poPluralForms(lang, forms)
+		nplurals, f, err := parsePluralFormsHeader(header)
+		if err != nil {
+			t.Errorf("%s: %v", lang, err)
+			continue
+		}
+		if nplurals != len(forms) || !pluralExprMatches(f, lang, forms) {
+			t.Errorf("%s: Plural-Forms %q doesn't match CLDR forms %v", lang, header, forms)
+		}
+		if !slices.Equal(poFormsOrder(lang, header), forms) {
+			t.Errorf("%s: poFormsOrder(%q) = %v, want %v", lang, header, poFormsOrder(lang, header), forms)
+		}
+	}
+}
+
+func TestPOFormsOrder(t *testing.T) {
+	for _, tc := range []struct {
+		lang, header string
+		want         []string
+	}{
+		{"cs", "nplurals=3; plural=(n==1) ? 0 : (n>=2 && n<=4) ? 1 : 2;", []string{"one", "few", "other"}},
+		{"ru", "nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);", []string{"one", "few", "many"}},
+		{"de", "nplurals=2; plural=n == 1 ? 1 : 0;", []string{"other", "one"}},
+		{"de", "", []string{"one", "other"}},
+		{"de", "nplurals=2; plural=n +;", []string{"one", "other"}},
+	} {
+		if got := poFormsOrder(tc.lang, tc.header); !slices.Equal(got, tc.want) {
+			t.Errorf("poFormsOrder(%s, %q) = %v, want %v", tc.lang, tc.header, got, tc.want)
+		}
+	}
+}
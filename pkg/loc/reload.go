@@ -0,0 +1,225 @@
+package loc
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls if not given a positive interval.
+const DefaultWatchInterval = 2 * time.Second
+
+// Reload re-reads the catalog's source, and swaps the result in at once. Every module previously loaded is read for
+// every language directory currently in the source, along with any module file added since. Files which were
+// removed are dropped. Files which fail to decode keep their previously loaded data;
+// their errors are joined into the returned error.
+func (c *Catalog) Reload() error {
+	c.mu.RLock()
+	old := make(map[fileKey]Translation, len(c.files))
+	modules := make(map[string]struct{})
+	for k, v := range c.files {
+		old[k] = v
+		modules[k.module] = struct{}{}
+	}
+	files := make(map[fileKey]Translation, len(old))
+	for k, v := range c.static {
+		files[k] = v
+	}
+	c.mu.RUnlock()
+
+	fsys := c.source()
+	format := c.fileFormat()
+	langs, err := langDirs(fsys)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	// pick up module files added since, under the module name they were loaded as before, if any.
+	stems := make(map[string]struct{}, len(modules))
+	for moduleName := range modules {
+		stems[trimExt(moduleName)] = struct{}{}
+	}
+	for _, lang := range langs {
+		err := walkModules(fsys, lang, func(file string) error {
+			if _, ok := stems[trimExt(file)]; !ok {
+				stems[trimExt(file)] = struct{}{}
+				modules[moduleOf(file)] = struct{}{}
+			}
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, lang := range langs {
+		for moduleName := range modules {
+			key := fileKey{lang: lang, module: moduleName}
+			xmlData, err := readModule(fsys, format, lang, moduleName)
+			switch {
+			case err == nil:
+				files[key] = xmlData
+			case errors.Is(err, fs.ErrNotExist):
+				// file was removed; drop it, keeping any compiled in data.
+			default:
+				errs = append(errs, err)
+				if prev, ok := old[key]; ok {
+					files[key] = prev
+				}
+			}
+		}
+	}
+
+	data := make(map[string]map[string]Value)
+	dataCount := make(map[string]int)
+	for key, xmlData := range files {
+		if _, ok := data[key.lang]; !ok {
+			data[key.lang] = make(map[string]Value)
+		}
+		for _, row := range xmlData.Rows {
+			if row.Name == "" { // ignore empties
+				continue
+			}
+			data[key.lang][row.Name] = row
+		}
+		count := xmlData.Counter
+		if count <= 0 {
+			count = len(xmlData.Rows)
+		}
+		if dataCount[key.module] < count {
+			dataCount[key.module] = count
+		}
+	}
+
+	c.mu.Lock()
+	for k, v := range c.dataCount { // never hand out an id twice
+		if dataCount[k] < v {
+			dataCount[k] = v
+		}
+	}
+	c.data = data
+	c.dataCount = dataCount
+	c.files = files
+	c.languages = nil
+	c.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Watch polls the catalog's source every interval, or DefaultWatchInterval if it isn't positive, and reloads the
+// catalog whenever a file has been added, removed or modified. It blocks until ctx is done. Reload errors are passed
+// to onErr, or logged if onErr is nil.
+func (c *Catalog) Watch(ctx context.Context, interval time.Duration, onErr func(error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	if onErr == nil {
+		onErr = func(err error) {
+			Logger.Error().Err(err).Msg("failed to reload translations")
+		}
+	}
+
+	last, err := snapshotFS(c.source())
+	if err != nil {
+		onErr(err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		curr, err := snapshotFS(c.source())
+		if err != nil {
+			onErr(err)
+			continue
+		}
+		if snapshotsEqual(last, curr) {
+			continue
+		}
+		last = curr
+
+		Logger.Debug().Msg("translation files changed; reloading")
+		if err := c.Reload(); err != nil {
+			onErr(err)
+		}
+	}
+}
+
+func langDirs(fsys fs.FS) ([]string, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var langs []string
+	for _, x := range files {
+		if !x.IsDir() || strings.HasPrefix(x.Name(), ".") {
+			// if not a directory, or is hidden, skip
+			continue
+		}
+		langs = append(langs, x.Name())
+	}
+	return langs, nil
+}
+
+func trimExt(name string) string {
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func snapshotFS(fsys fs.FS) (map[string]fileState, error) {
+	snap := make(map[string]fileState)
+	err := fs.WalkDir(fsys, ".", func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		snap[fpath] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return snap, nil
+	}
+	return snap, err
+}
+
+func snapshotsEqual(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !v.modTime.Equal(w.modTime) || v.size != w.size {
+			return false
+		}
+	}
+	return true
+}
+
+func Reload() error {
+	return pkgCatalog().Reload()
+}
+
+func Watch(ctx context.Context, interval time.Duration, onErr func(error)) {
+	pkgCatalog().Watch(ctx, interval, onErr)
+}
@@ -0,0 +1,120 @@
+package loc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCatalogReload(t *testing.T) {
+	fsys := fstest.MapFS{
+		"en-US/bot/main.xml": xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "hello"}),
+	}
+	c := NewCatalogFS("en-US", fsys)
+	c.Load("bot/main.go")
+
+	// changed, added modules and added languages are all picked up.
+	fsys["en-US/bot/main.xml"] = xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "hi"})
+	fsys["en-US/bot/admin.xml"] = xmlFile(Value{Id: 1, Name: "bot/admin.go:1", Value: "banned"})
+	fsys["de-DE/bot/main.xml"] = xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "hallo"})
+	if err := c.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ lang, key, want string }{
+		{"en-US", "bot/main.go:1", "hi"},
+		{"en-US", "bot/admin.go:1", "banned"},
+		{"de-DE", "bot/main.go:1", "hallo"},
+	} {
+		if got := c.Trnl(tc.lang, tc.key); got != tc.want {
+			t.Errorf("after reload, Trnl(%s, %s) = %q, want %q", tc.lang, tc.key, got, tc.want)
+		}
+	}
+
+	// a file which fails to decode keeps its previous data; the others are still swapped in.
+	fsys["en-US/bot/main.xml"] = &fstest.MapFile{Data: []byte("<translation><row>")}
+	fsys["de-DE/bot/main.xml"] = xmlFile(Value{Id: 1, Name: "bot/main.go:1", Value: "servus"})
+	if err := c.Reload(); err == nil || !strings.Contains(err.Error(), "en-US/bot/main") {
+		t.Errorf("Reload() error = %v, want a decode error for en-US/bot/main", err)
+	}
+	if got := c.Trnl("en-US", "bot/main.go:1"); got != "hi" {
+		t.Errorf("after a decode error, Trnl(en-US) = %q, want the previous %q", got, "hi")
+	}
+	if got := c.Trnl("de-DE", "bot/main.go:1"); got != "servus" {
+		t.Errorf("after a decode error elsewhere, Trnl(de-DE) = %q, want %q", got, "servus")
+	}
+
+	// removed files are dropped.
+	delete(fsys, "en-US/bot/admin.xml")
+	_ = c.Reload()
+	if _, ok := c.Lookup("en-US", "bot/admin.go:1"); ok {
+		t.Error("removed module still loaded after reload")
+	}
+}
+
+func TestCatalogWatch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(value string) {
+		t.Helper()
+		tr := Translation{Counter: 1, Rows: []Value{{Id: 1, Name: "bot/main.go:1", Value: value}}}
+		if err := writeModuleFile(XMLFormat, filepath.Join(dir, "en-US", "bot", "main.xml"), tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("hello")
+	c := NewCatalog("en-US")
+	c.SetDir(dir)
+	c.Load("bot/main.go")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 10)
+	done := make(chan struct{})
+	go func() {
+		c.Watch(ctx, 10*time.Millisecond, func(err error) { errs <- err })
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for c.Trnl("en-US", "bot/main.go:1") != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Trnl() = %q, want %q after the file changed", c.Trnl("en-US", "bot/main.go:1"), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	time.Sleep(20 * time.Millisecond) // let Watch take its first snapshot
+	write("hello again, a longer value")
+	waitFor("hello again, a longer value")
+
+	// a broken file is reported, and the previous data kept.
+	if err := os.WriteFile(filepath.Join(dir, "en-US", "bot", "main.xml"), []byte("<translation><row>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "decode") {
+			t.Errorf("Watch reported %v, want a decode error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch did not report the broken file")
+	}
+	if got := c.Trnl("en-US", "bot/main.go:1"); got != "hello again, a longer value" {
+		t.Errorf("Trnl() = %q after a decode error, want the previous value", got)
+	}
+}
+
+func TestWatchDefaultInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// a zero interval must not panic; Watch returns as ctx is done.
+	NewCatalogFS("en-US", fstest.MapFS{}).Watch(ctx, 0, nil)
+}
@@ -0,0 +1,94 @@
+package loc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Select chooses between variants of a message based on a named argument passed to Trnlf, such as a gender.
+// The "other" case is used when the argument is missing or matches no case.
+type Select struct {
+	Arg   string `xml:"arg,attr" json:"arg" yaml:"arg"`
+	Cases []Case `xml:"case" json:"cases" yaml:"cases"`
+}
+
+// Case is a single variant of a Select.
+type Case struct {
+	Key   string `xml:"key,attr" json:"key" yaml:"key"`
+	Value string `xml:",chardata" json:"value" yaml:"value"`
+}
+
+// Case returns the text of the given case, or an empty string if it isn't set.
+func (s *Select) Case(key string) string {
+	for _, c := range s.Cases {
+		if c.Key == key {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// pick returns the text of the case matching args, falling back to the "other" case.
+func (s *Select) pick(args map[string]string) string {
+	if key, ok := args[s.Arg]; ok {
+		if text := s.Case(key); text != "" {
+			return text
+		}
+	}
+	return s.Case("other")
+}
+
+func (s *Select) keys() map[string]struct{} {
+	out := make(map[string]struct{}, len(s.Cases))
+	for _, c := range s.Cases {
+		out[c.Key] = struct{}{}
+	}
+	return out
+}
+
+func (s *Select) String() string {
+	parts := make([]string, 0, len(s.Cases))
+	for _, c := range s.Cases {
+		parts = append(parts, c.Key+": "+c.Value)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// text returns the text of v for the given Trnlf arguments, resolving any Select.
+func (v Value) text(args map[string]string) string {
+	if v.Select != nil {
+		return v.Select.pick(args)
+	}
+	return v.Value
+}
+
+// checkSelect makes sure custom covers exactly the same select cases as def, and that each case is valid.
+func checkSelect(def *Select, custom *Select) error {
+	if custom == nil {
+		return fmt.Errorf("missing select on '%s'", def.Arg)
+	}
+	if def.Arg != custom.Arg {
+		return fmt.Errorf("selects on '%s', should select on '%s'", custom.Arg, def.Arg)
+	}
+	defKeys := def.keys()
+	customKeys := custom.keys()
+	for k := range defKeys {
+		if _, ok := customKeys[k]; !ok {
+			return fmt.Errorf("missing select case '%s'", k)
+		}
+	}
+	for k := range customKeys {
+		if _, ok := defKeys[k]; !ok {
+			return fmt.Errorf("unknown select case '%s'", k)
+		}
+	}
+	for _, c := range custom.Cases {
+		if c.Value == "" {
+			continue
+		}
+		if err := checkCurlies(def.Case(c.Key), c.Value); err != nil {
+			return fmt.Errorf("case '%s': %w", c.Key, err)
+		}
+	}
+	return nil
+}
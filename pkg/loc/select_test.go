@@ -0,0 +1,56 @@
+package loc
+
+import (
+	"testing"
+)
+
+func TestCatalogSelect(t *testing.T) {
+	c := NewCatalog("en-US")
+	c.add("en-US", "mod.go", Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Select: &Select{
+		Arg: "gender",
+		Cases: []Case{
+			{Key: "male", Value: "He replied to {name}"},
+			{Key: "female", Value: "She replied to {name}"},
+			{Key: "other", Value: "They replied to {name}"},
+		},
+	}}}})
+
+	tests := []struct {
+		gender string
+		want   string
+	}{
+		{gender: "male", want: "He replied to Bob"},
+		{gender: "female", want: "She replied to Bob"},
+		{gender: "unknown", want: "They replied to Bob"},
+	}
+	for _, tc := range tests {
+		got := c.Trnlf("en-US", "mod.go:1", map[string]string{"gender": tc.gender, "name": "Bob"})
+		if got != tc.want {
+			t.Errorf("Trnlf(gender=%s) = %q, want %q", tc.gender, got, tc.want)
+		}
+	}
+	if got := c.Trnl("en-US", "mod.go:1"); got != "They replied to {name}" {
+		t.Errorf("Trnl() = %q, want the other case", got)
+	}
+}
+
+func TestCheckSelect(t *testing.T) {
+	def := &Select{Arg: "gender", Cases: []Case{{Key: "male", Value: "He {1}"}, {Key: "other", Value: "They {1}"}}}
+	tests := []struct {
+		name    string
+		custom  *Select
+		wantErr bool
+	}{
+		{name: "ok", custom: &Select{Arg: "gender", Cases: []Case{{Key: "male", Value: "Er {1}"}, {Key: "other", Value: ""}}}},
+		{name: "missing", custom: nil, wantErr: true},
+		{name: "wrong arg", custom: &Select{Arg: "sex", Cases: def.Cases}, wantErr: true},
+		{name: "missing case", custom: &Select{Arg: "gender", Cases: []Case{{Key: "other", Value: "Sie {1}"}}}, wantErr: true},
+		{name: "extra case", custom: &Select{Arg: "gender", Cases: append([]Case{{Key: "female"}}, def.Cases...)}, wantErr: true},
+		{name: "curlies", custom: &Select{Arg: "gender", Cases: []Case{{Key: "male", Value: "Er"}, {Key: "other"}}}, wantErr: true},
+	}
+	for _, tc := range tests {
+		if err := checkSelect(def, tc.custom); (err != nil) != tc.wantErr {
+			t.Errorf("%s: checkSelect() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
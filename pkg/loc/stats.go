@@ -0,0 +1,159 @@
+package loc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Stats counts the entries of a language, either in a single module or over all modules.
+type Stats struct {
+	Lang   string `json:"lang"`
+	Module string `json:"module,omitempty"` // empty for the language total
+	// Total is the number of keys in the default language.
+	Total      int `json:"total"`
+	Translated int `json:"translated"`
+	// Missing keys have no entry in the language's files at all.
+	Missing int `json:"missing"`
+	// Empty keys have an entry, but some or all of its text is blank.
+	Empty int `json:"empty"`
+	// Identical keys are fully translated, but the same as the default language.
+	Identical int `json:"identical"`
+	// Stale entries are no longer in the default language, or have a different id.
+	Stale int `json:"stale"`
+}
+
+// Coverage returns the share of keys which have a translation, including those identical to the source.
+func (s Stats) Coverage() float64 {
+	if s.Total == 0 {
+		return 1
+	}
+	return float64(s.Translated+s.Identical) / float64(s.Total)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Total += o.Total
+	s.Translated += o.Translated
+	s.Missing += o.Missing
+	s.Empty += o.Empty
+	s.Identical += o.Identical
+	s.Stale += o.Stale
+}
+
+// Stats counts the entries of every language other than the default one. Each language's total is followed by the
+// counts for each of its modules.
+func (l *Locer) Stats() ([]Stats, error) {
+	sets, langs, err := l.loadModuleSets()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Stats
+	for _, lang := range langs {
+		total := Stats{Lang: lang}
+		var modules []Stats
+		for _, set := range sets {
+			s := moduleStats(set, lang)
+			total.add(s)
+			modules = append(modules, s)
+		}
+		out = append(append(out, total), modules...)
+	}
+	return out, nil
+}
+
+func moduleStats(set moduleSet, lang string) Stats {
+	s := Stats{Lang: lang, Module: set.name}
+	have := make(map[string]Value)
+	for _, row := range set.langs[lang].Rows {
+		if row.Name != "" {
+			have[row.Name] = row
+		}
+	}
+	defIds := make(map[string]int)
+	for _, def := range set.def.Rows {
+		if def.Name == "" {
+			continue
+		}
+		defIds[def.Name] = def.Id
+		s.Total++
+
+		cur, ok := have[def.Name]
+		if !ok {
+			s.Missing++
+			continue
+		}
+		filled, identical := true, true
+		for _, u := range units(def, cur, lang) {
+			filled = filled && u.target != ""
+			identical = identical && u.target == u.source
+		}
+		switch {
+		case !filled:
+			s.Empty++
+		case identical:
+			s.Identical++
+		default:
+			s.Translated++
+		}
+	}
+	for name, row := range have {
+		if id, ok := defIds[name]; !ok || id != row.Id {
+			s.Stale++
+		}
+	}
+	return s
+}
+
+// WriteStats writes stats as an aligned table ("table"), JSON ("json") or CSV ("csv").
+func WriteStats(w io.Writer, format string, stats []Stats) error {
+	header := []string{"lang", "module", "total", "translated", "missing", "empty", "identical", "stale", "coverage"}
+	row := func(s Stats) []string {
+		module := s.Module
+		coverage := strconv.FormatFloat(s.Coverage()*100, 'f', 1, 64)
+		if format == "table" {
+			if module == "" {
+				module = "(all)"
+			}
+			coverage += "%"
+		}
+		return []string{s.Lang, module, strconv.Itoa(s.Total), strconv.Itoa(s.Translated), strconv.Itoa(s.Missing),
+			strconv.Itoa(s.Empty), strconv.Itoa(s.Identical), strconv.Itoa(s.Stale), coverage}
+	}
+
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, s := range stats {
+			fmt.Fprintln(tw, strings.Join(row(s), "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		type jsonStats struct {
+			Stats
+			Coverage float64 `json:"coverage"`
+		}
+		out := make([]jsonStats, 0, len(stats))
+		for _, s := range stats {
+			out = append(out, jsonStats{Stats: s, Coverage: s.Coverage()})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write(header)
+		for _, s := range stats {
+			_ = cw.Write(row(s))
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown stats format '%s'", format)
+	}
+}
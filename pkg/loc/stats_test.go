@@ -0,0 +1,47 @@
+package loc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	tree := testTree()
+	tree["de-DE/bot/main.xml"] = Translation{Counter: 3, Rows: []Value{
+		{Id: 1, Name: "bot/main.go:1", Value: "hallo {1}"},
+		{Id: 2, Name: "bot/main.go:2", Plurals: []Plural{{Form: "one", Value: "{1} file"}, {Form: "other", Value: "{1} files"}}},
+		{Id: 3, Name: "bot/main.go:3", Select: &Select{Arg: "g", Cases: []Case{{Key: "male", Value: "er"}, {Key: "other"}}}},
+		{Id: 9, Name: "bot/main.go:9", Value: "weg"},
+	}}
+	tree["fr-FR/bot/other.xml"] = Translation{}
+	writeTestTree(t, dir, tree)
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+
+	stats, err := l.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Stats{
+		{Lang: "de-DE", Total: 3, Translated: 1, Empty: 1, Identical: 1, Stale: 1},
+		{Lang: "de-DE", Module: "bot/main.xml", Total: 3, Translated: 1, Empty: 1, Identical: 1, Stale: 1},
+		{Lang: "fr-FR", Total: 3, Missing: 3},
+		{Lang: "fr-FR", Module: "bot/main.xml", Total: 3, Missing: 3},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %d stats, want %d: %+v", len(stats), len(want), stats)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+
+	var sb strings.Builder
+	if err := WriteStats(&sb, "csv", stats[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sb.String(), "lang,module,total,translated,missing,empty,identical,stale,coverage\nde-DE,,3,1,0,1,1,1,66.7\n"; got != want {
+		t.Errorf("csv = %q, want %q", got, want)
+	}
+}
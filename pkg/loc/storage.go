@@ -0,0 +1,201 @@
+package loc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileFormat reads and writes translation files.
+type FileFormat interface {
+	// Name identifies the format in configuration, eg "xml".
+	Name() string
+	// Ext is the extension of files in this format, including the dot.
+	Ext() string
+	Decode(r io.Reader) (Translation, error)
+	Encode(w io.Writer, t Translation) error
+}
+
+var (
+	XMLFormat  FileFormat = xmlFormat{}
+	JSONFormat FileFormat = jsonFormat{}
+	YAMLFormat FileFormat = yamlFormat{}
+)
+
+// fileFormats lists every FileFormat, in the order they are tried when loading a module.
+var fileFormats = []FileFormat{XMLFormat, JSONFormat, YAMLFormat}
+
+// FileFormatByName returns the FileFormat called name; an empty name is XML.
+func FileFormatByName(name string) (FileFormat, error) {
+	if name == "" {
+		return XMLFormat, nil
+	}
+	for _, f := range fileFormats {
+		if f.Name() == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown translation file format '%s'", name)
+}
+
+type xmlFormat struct{}
+
+func (xmlFormat) Name() string { return "xml" }
+func (xmlFormat) Ext() string  { return ".xml" }
+
+func (xmlFormat) Decode(r io.Reader) (Translation, error) {
+	var t Translation
+	err := xml.NewDecoder(r).Decode(&t)
+	return t, err
+}
+
+func (xmlFormat) Encode(w io.Writer, t Translation) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonFormat struct{}
+
+func (jsonFormat) Name() string { return "json" }
+func (jsonFormat) Ext() string  { return ".json" }
+
+func (jsonFormat) Decode(r io.Reader) (Translation, error) {
+	var t Translation
+	err := json.NewDecoder(r).Decode(&t)
+	return t, err
+}
+
+func (jsonFormat) Encode(w io.Writer, t Translation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(t)
+}
+
+type yamlFormat struct{}
+
+func (yamlFormat) Name() string { return "yaml" }
+func (yamlFormat) Ext() string  { return ".yaml" }
+
+func (yamlFormat) Decode(r io.Reader) (Translation, error) {
+	var t Translation
+	err := yaml.NewDecoder(r).Decode(&t)
+	if errors.Is(err, io.EOF) {
+		// an empty document is an empty module
+		err = nil
+	}
+	return t, err
+}
+
+func (yamlFormat) Encode(w io.Writer, t Translation) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(t); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// moduleFile returns the path of the translation file of moduleName for lang, relative to the translation directory.
+func moduleFile(format FileFormat, lang string, moduleName string) string {
+	return path.Join(lang, strings.TrimSuffix(moduleName, path.Ext(moduleName))+format.Ext())
+}
+
+// isModuleFile reports whether name is a translation file in the given format.
+func isModuleFile(format FileFormat, name string) bool {
+	return path.Ext(name) == format.Ext()
+}
+
+// formatOf returns the FileFormat of the translation file name, or nil if it isn't one.
+func formatOf(name string) FileFormat {
+	for _, format := range fileFormats {
+		if isModuleFile(format, name) {
+			return format
+		}
+	}
+	return nil
+}
+
+// moduleOf returns the name of the module stored in the translation file name, relative to its language directory;
+// this is the Go source file its strings were extracted from.
+func moduleOf(name string) string {
+	return trimExt(name) + ".go"
+}
+
+// walkModules calls fn with every translation file under lang in fsys, relative to lang. Files in any format are
+// walked, once per module, so a module stored in a format other than the preferred one isn't missed.
+func walkModules(fsys fs.FS, lang string, fn func(file string) error) error {
+	seen := make(map[string]struct{})
+	return fs.WalkDir(fsys, lang, func(fpath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || formatOf(fpath) == nil {
+			return nil
+		}
+		file := strings.TrimPrefix(fpath, lang+"/")
+		if _, ok := seen[trimExt(file)]; ok {
+			return nil
+		}
+		seen[trimExt(file)] = struct{}{}
+		return fn(file)
+	})
+}
+
+// readModule decodes the translation file of moduleName for lang. The file is looked for in format first, then in
+// every other format, so a project can move between formats one file at a time.
+func readModule(fsys fs.FS, format FileFormat, lang string, moduleName string) (Translation, error) {
+	formats := append([]FileFormat{format}, fileFormats...)
+	var err error
+	for _, f := range formats {
+		var t Translation
+		t, err = readModuleFile(fsys, f, lang, moduleName)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return t, err
+		}
+	}
+	return Translation{}, err
+}
+
+func readModuleFile(fsys fs.FS, format FileFormat, lang string, moduleName string) (Translation, error) {
+	f, err := fsys.Open(moduleFile(format, lang, moduleName))
+	if err != nil {
+		return Translation{}, fmt.Errorf("failed to open file at %s: %w", moduleName, err)
+	}
+	defer ioClose(f)
+	t, err := format.Decode(f)
+	if err != nil {
+		return t, fmt.Errorf("failed to decode data for %s/%s: %w", lang, moduleName, err)
+	}
+	return t, nil
+}
+
+// writeModuleFile writes t to filename, creating any missing directories.
+func writeModuleFile(format FileFormat, filename string, t Translation) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer ioClose(f)
+	return format.Encode(f, t)
+}
@@ -0,0 +1,101 @@
+package loc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/text/language"
+)
+
+func TestFileFormatRoundTrip(t *testing.T) {
+	want := Translation{Counter: 3, Rows: []Value{
+		{Id: 1, Name: "mod.go:1", Value: "hello <{1}> & \"bye\"", Comment: "mod.go:1"},
+		{Id: 2, Name: "mod.go:2", State: StateReviewed, Plurals: []Plural{{Form: "one", Value: "{1} file"}, {Form: "other", Value: "{1} files"}}},
+		{Id: 3, Name: "mod.go:3", Select: &Select{Arg: "g", Cases: []Case{{Key: "other", Value: "they"}}}},
+	}}
+	for _, format := range fileFormats {
+		t.Run(format.Name(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := format.Encode(&buf, want); err != nil {
+				t.Fatal(err)
+			}
+			got, err := format.Decode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.XMLName = want.XMLName
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCatalogFileFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := YAMLFormat.Encode(&buf, Translation{Rows: []Value{{Id: 1, Name: "mod.go:1", Value: "hallo"}}}); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"en-US/mod.xml":  xmlFile(Value{Id: 1, Name: "mod.go:1", Value: "hello"}),
+		"de-DE/mod.yaml": &fstest.MapFile{Data: buf.Bytes()},
+	}
+
+	c := NewCatalogFS("en-US", fsys)
+	c.SetFileFormat(YAMLFormat)
+	c.Load("mod.go")
+	if got := c.Trnl("de-DE", "mod.go:1"); got != "hallo" {
+		t.Errorf("Trnl(de-DE) = %q, want %q", got, "hallo")
+	}
+	// modules missing in the preferred format are read from any other format
+	if got := c.Trnl("en-US", "mod.go:1"); got != "hello" {
+		t.Errorf("Trnl(en-US) = %q, want %q", got, "hello")
+	}
+}
+
+func TestMixedFormatTree(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, testTree())
+	for name, tr := range map[string]Translation{
+		"en-GB/bot/admin.json": {Counter: 1, Rows: []Value{{Id: 1, Name: "bot/admin.go:1", Value: "banned"}}},
+		"de-DE/bot/admin.json": {Counter: 1, Rows: []Value{{Id: 1, Name: "bot/admin.go:1", Value: "gesperrt"}}},
+	} {
+		if err := writeModuleFile(JSONFormat, filepath.Join(dir, filepath.FromSlash(name)), tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewCatalog("en-GB")
+	c.SetDir(dir)
+	c.LoadAll("en-GB")
+	c.LoadLangAll("de-DE")
+	for _, tc := range []struct{ lang, key, want string }{
+		{"en-GB", "bot/main.go:1", "hello {1}"},
+		{"en-GB", "bot/admin.go:1", "banned"},
+		{"de-DE", "bot/admin.go:1", "gesperrt"},
+	} {
+		if got := c.Trnl(tc.lang, tc.key); got != tc.want {
+			t.Errorf("Trnl(%s, %s) = %q, want %q", tc.lang, tc.key, got, tc.want)
+		}
+	}
+
+	l := &Locer{DefaultLang: "en-GB", TransDir: dir}
+	sets, _, err := l.loadModuleSets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 2 {
+		t.Errorf("loaded %d module sets, want 2", len(sets))
+	}
+
+	l.Create(nil, language.MustParse("fr-FR"))
+	for _, name := range []string{"fr-FR/bot/main.xml", "fr-FR/bot/admin.xml"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("create didn't write %s: %v", name, err)
+		}
+	}
+}
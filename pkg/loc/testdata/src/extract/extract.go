@@ -0,0 +1,22 @@
+package extract
+
+import "fmt"
+
+type bot struct{}
+
+func (bot) Send(text string)                                    {}
+func (bot) Sendf(format string, args ...interface{})            {}
+func (bot) Sendp(one, other string, n int, args ...interface{}) {}
+
+func getLang(u string) string { return u }
+
+func greet(b bot, u string, name string, n int) {
+	b.Send("hello there")             // want `unlocalised string passed to b.Send`
+	b.Sendf("hello %s", name)         // want `unlocalised string passed to b.Sendf`
+	b.Sendp("%d file", "%d files", n) // want `unlocalised string passed to b.Sendp`
+	b.Send(fmt.Sprint("not", "a", "literal"))
+}
+
+func announce(b bot) { // want `no lang expression applies in announce`
+	b.Send("no user to ask") // want `unlocalised string passed to b.Send`
+}
@@ -1,45 +1,87 @@
 package loc
 
 import (
-	"encoding/xml"
 	"fmt"
 	"io"
-	"os"
-	"path"
-	"path/filepath"
-	"sort"
-	"strings"
+	"io/fs"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 )
 
 var (
-	data        = make(map[string]map[string]Value)
-	dataCount   = make(map[string]int)
-	languages   []string
-	DefaultLang = "en-US"
-	Logger      *zerolog.Logger
+	defaultCatalog = NewCatalog(RuntimeDefaultLanguage)
+	Logger         *zerolog.Logger
 )
 
-func Trnl(lang string, trnlVal string) string {
-	v, ok := data[lang][trnlVal]
-	if !ok || v.Value == "" {
-		return data[DefaultLang][trnlVal].Value
+// DefaultLang is the default language of the package level functions.
+//
+// Deprecated: use SetDefaultLang, and DefaultCatalog().DefaultLang() to read it. Assigning DefaultLang still takes
+// effect, but is not safe while translating concurrently.
+var DefaultLang = RuntimeDefaultLanguage
+
+var (
+	defaultLangMu   sync.Mutex             // serializes changes of the default language
+	defaultLangSeen atomic.Pointer[string] // the value of DefaultLang last given to defaultCatalog
+)
+
+func init() {
+	lang := DefaultLang
+	defaultLangSeen.Store(&lang)
+}
+
+// DefaultCatalog returns the Catalog used by the package level functions.
+func DefaultCatalog() *Catalog {
+	return pkgCatalog()
+}
+
+// pkgCatalog returns defaultCatalog, after giving it the value assigned to DefaultLang, if changed. Unless it did
+// change, this takes no lock.
+func pkgCatalog() *Catalog {
+	if DefaultLang != *defaultLangSeen.Load() {
+		defaultLangMu.Lock()
+		if lang := DefaultLang; lang != *defaultLangSeen.Load() {
+			defaultLangSeen.Store(&lang)
+			defaultCatalog.SetDefaultLang(lang)
+		}
+		defaultLangMu.Unlock()
 	}
-	return v.Value
+	return defaultCatalog
+}
+
+func SetDefaultLang(lang string) {
+	defaultLangMu.Lock()
+	defer defaultLangMu.Unlock()
+	DefaultLang = lang
+	defaultLangSeen.Store(&lang)
+	defaultCatalog.SetDefaultLang(lang)
+}
+
+// SetTranslationDir makes the package level loaders read from dir on disk, instead of DefaultTranslationDir.
+func SetTranslationDir(dir string) {
+	pkgCatalog().SetDir(dir)
+}
+
+// SetFS makes the package level loaders read from fsys, such as an embed.FS, instead of the translation directory.
+func SetFS(fsys fs.FS) {
+	pkgCatalog().SetFS(fsys)
+}
+
+func AddModule(lang string, moduleName string, t Translation) {
+	pkgCatalog().AddModule(lang, moduleName, t)
+}
+
+func SetFileFormat(format FileFormat) {
+	pkgCatalog().SetFileFormat(format)
+}
+
+func Trnl(lang string, trnlVal string) string {
+	return pkgCatalog().Trnl(lang, trnlVal)
 }
 
 func Trnlf(lang string, trnlVal string, dataMap map[string]string) string {
-	var replData []string
-	for k, v := range dataMap {
-		replData = append(replData, "{"+k+"}", v)
-	}
-	repl := strings.NewReplacer(replData...)
-	v, ok := data[lang][trnlVal]
-	if !ok || v.Value == "" {
-		return repl.Replace(data[DefaultLang][trnlVal].Value)
-	}
-	return repl.Replace(v.Value)
+	return pkgCatalog().Trnlf(lang, trnlVal, dataMap)
 }
 
 func Add(text string) string {
@@ -53,48 +95,11 @@ func Addf(text string, format ...interface{}) string {
 }
 
 func LoadAll(defLang string) {
-	base := path.Join(translationDir, defLang)
-	err := filepath.Walk(base,
-		func(fpath string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			relPath, err := filepath.Rel(base, fpath)
-			if err != nil {
-				Logger.Error().Err(err).Msgf("Could not get relative path of %s", fpath)
-			}
-			Load(relPath)
-			return nil
-		})
-	if err != nil {
-		Logger.Error().Err(err).Msgf("Failed to walk translations directory %s", base)
-	}
+	pkgCatalog().LoadAll(defLang)
 }
 
 func LoadLangAll(lang string) {
-	base := path.Join(translationDir, lang)
-	err := filepath.Walk(base,
-		func(fpath string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			relPath, err := filepath.Rel(base, fpath)
-			if err != nil {
-				Logger.Error().Err(err).Msgf("Could not get relative path of %s", fpath)
-			}
-			LoadLangModule(lang, relPath)
-			// Load(info.Name())
-			return nil
-		})
-	if err != nil {
-		Logger.Error().Err(err).Msgf("Failed to walk translations directory %s", base)
-	}
+	pkgCatalog().LoadLangAll(lang)
 }
 
 func ioClose(f io.Closer) {
@@ -104,73 +109,17 @@ func ioClose(f io.Closer) {
 }
 
 func LoadLangModule(lang string, moduleName string) {
-	f, err := os.Open(path.Join(translationDir, lang, strings.TrimSuffix(moduleName, path.Ext(moduleName))+".xml"))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
-		Logger.Error().Err(err).Msgf("Failed to open file at %s", moduleName)
-		return
-	}
-	defer ioClose(f)
-	dec := xml.NewDecoder(f)
-	var xmlData Translation
-	err = dec.Decode(&xmlData)
-	if err != nil {
-		Logger.Error().Err(err).Msgf("Failed to decode data for %s", moduleName)
-		return
-	}
-	for _, row := range xmlData.Rows {
-		if _, ok := data[path.Base(lang)]; !ok {
-			data[path.Base(lang)] = make(map[string]Value)
-		}
-		if row.Name == "" { // ignore empties
-			continue
-		}
-		data[path.Base(lang)][row.Name] = row
-	}
-	count := xmlData.Counter
-	if count <= 0 {
-		count = len(xmlData.Rows)
-	}
-	if dataCount[moduleName] < count {
-		dataCount[moduleName] = count
-	}
+	pkgCatalog().LoadLangModule(lang, moduleName)
 }
 
 func Load(moduleToLoad string) {
-	files, err := os.ReadDir(translationDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return
-		}
-
-		Logger.Error().Err(err).Msgf("failed to load %s", moduleToLoad)
-		return
-	}
-	for _, x := range files {
-		if !x.IsDir() || strings.HasPrefix(x.Name(), ".") {
-			// if not a directory, or is hidden, skip
-			continue
-		}
-
-		LoadLangModule(x.Name(), moduleToLoad)
-	}
+	pkgCatalog().Load(moduleToLoad)
 }
 
-func Languages() (ss []string) {
-	if languages != nil {
-		return languages
-	}
-	for k := range data {
-		ss = append(ss, k)
-	}
-	sort.Strings(ss)
-	languages = ss
-	return ss
+func Languages() []string {
+	return pkgCatalog().Languages()
 }
 
 func IsLangSupported(s string) bool {
-	_, ok := data[s]
-	return ok
+	return pkgCatalog().IsLangSupported(s)
 }
@@ -1,75 +1,345 @@
 package loc
 
 import (
-	"encoding/xml"
+	"errors"
+	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
+	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
 	"slices"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-func parseFmtString(rdata []rune, ret *ast.CallExpr) (newData []rune, mapData []ast.Expr, needStrconv bool) {
-	index := 1
-	for i := 0; i < len(rdata); i++ {
-		if rdata[i] == '%' && i+1 < len(rdata) {
+// fmtVerbs holds every verb accepted by the fmt package; %w is only valid in fmt.Errorf, and is treated as %v.
+const fmtVerbs = "vTtbcdoOqxXUeEfFgGspw"
+
+// parseFmtString converts a fmt format string into a string of {n} placeholders, along with the map entries
+// needed to fill them from the arguments of ret, whose first argument is the format string itself.
+// Plain %s, %d, %t and %v are converted directly when the argument type allows it; any other directive (flags,
+// width, precision, other verbs, or arguments of unknown type) is wrapped in an equivalent fmt.Sprintf call so
+// formatting is unchanged. Explicit argument indexes such as %[2]d
+// are followed, and repeated uses of the same argument share a placeholder.
+func parseFmtString(rdata []rune, ret *ast.CallExpr) (newData []rune, mapData []ast.Expr, imports []string, err error) {
+	ph := newFmtPlaceholders()
+	newData, err = ph.convert(rdata, ret)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return newData, ph.mapData, ph.imports(), nil
+}
+
+// fmtPlaceholders collects the placeholders of one or more format strings sharing the same arguments, such as the
+// forms of a plural, so the same argument always gets the same placeholder.
+type fmtPlaceholders struct {
+	index      map[string]int // value expression:placeholder number
+	mapData    []ast.Expr
+	needImport map[string]struct{}
+	info       *types.Info // type information of the arguments, if loaded
+	// typedNumbers marks %d placeholders as {n,number}, so Trnlf formats them for the target language.
+	typedNumbers bool
+}
+
+func newFmtPlaceholders() *fmtPlaceholders {
+	return &fmtPlaceholders{
+		index:      make(map[string]int),
+		needImport: make(map[string]struct{}),
+	}
+}
+
+func (l *Locer) newFmtPlaceholders() *fmtPlaceholders {
+	ph := newFmtPlaceholders()
+	ph.typedNumbers = l.FormatNumbers
+	ph.info = l.info
+	return ph
+}
+
+func (ph *fmtPlaceholders) imports() (out []string) {
+	for imp := range ph.needImport {
+		out = append(out, imp)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (ph *fmtPlaceholders) convert(rdata []rune, ret *ast.CallExpr) (newData []rune, err error) {
+	if ret.Ellipsis.IsValid() {
+		return nil, errors.New("cannot convert a format call using variadic ... arguments")
+	}
+	var fmtArgs []ast.Expr
+	if len(ret.Args) > 0 {
+		fmtArgs = ret.Args[1:]
+	}
+	argNum := 0 // index into fmtArgs of the next argument to use
+
+	getArg := func(directive string) (ast.Expr, error) {
+		if argNum >= len(fmtArgs) {
+			return nil, fmt.Errorf("directive '%s' needs argument %d, but only %d given", directive, argNum+1, len(fmtArgs))
+		}
+		arg := fmtArgs[argNum]
+		argNum++
+		return arg, nil
+	}
+	// argIndex parses an explicit [n] argument index at rdata[i], if any, and returns the index after it.
+	argIndex := func(i int) (int, error) {
+		if i >= len(rdata) || rdata[i] != '[' {
+			return i, nil
+		}
+		end := i + 1
+		for end < len(rdata) && rdata[end] != ']' {
+			end++
+		}
+		if end >= len(rdata) {
+			return i, errors.New("unterminated argument index")
+		}
+		n, err := strconv.Atoi(string(rdata[i+1 : end]))
+		if err != nil || n < 1 {
+			return i, fmt.Errorf("invalid argument index '%s'", string(rdata[i:end+1]))
+		}
+		argNum = n - 1
+		return end + 1, nil
+	}
+	digits := func(i int) int {
+		for i < len(rdata) && rdata[i] >= '0' && rdata[i] <= '9' {
 			i++
-			switch x := rdata[i]; x {
-			case 's': // string -> no change
-				mapData = append(mapData,
-					&ast.KeyValueExpr{
-						Key: &ast.BasicLit{
-							Kind:  token.STRING,
-							Value: strconv.Quote(strconv.Itoa(index)),
-						},
-						Value: ret.Args[index],
-					})
-			case 'd': // int
-				mapData = append(mapData,
-					&ast.KeyValueExpr{
-						Key: &ast.BasicLit{
-							Kind:  token.STRING,
-							Value: strconv.Quote(strconv.Itoa(index)),
-						},
-						Value: &ast.CallExpr{
-							Fun: &ast.SelectorExpr{
-								X:   &ast.Ident{Name: "strconv"},
-								Sel: &ast.Ident{Name: "Itoa"},
-							},
-							Args: []ast.Expr{ret.Args[index]},
-						},
-					})
-				needStrconv = true
-			case 't': // bool
-				mapData = append(mapData,
-					&ast.KeyValueExpr{
-						Key: &ast.BasicLit{
-							Kind:  token.STRING,
-							Value: `"` + strconv.Itoa(index) + `"`,
-						},
-						Value: &ast.CallExpr{
-							Fun: &ast.SelectorExpr{
-								X:   &ast.Ident{Name: "strconv"},
-								Sel: &ast.Ident{Name: "FormatBool"},
-							},
-							Args: []ast.Expr{ret.Args[index]},
-						},
-					})
-				// case 'p': // pointer (wtaf)
-				// strconv
-			default:
-				Logger.Fatal().Msgf("no way to handle '%s' formatting yet", string(x))
+		}
+		return i
+	}
+
+	for i := 0; i < len(rdata); i++ {
+		if rdata[i] != '%' {
+			newData = append(newData, rdata[i])
+			continue
+		}
+		start := i
+		i++
+
+		// spec is the directive without argument indexes, as used in the generated fmt.Sprintf call.
+		spec := []rune{'%'}
+		var specArgs []ast.Expr
+
+		flagsEnd := i
+		for flagsEnd < len(rdata) && strings.ContainsRune("#0+- ", rdata[flagsEnd]) {
+			flagsEnd++
+		}
+		spec = append(spec, rdata[i:flagsEnd]...)
+		if i, err = argIndex(flagsEnd); err != nil {
+			return nil, err
+		}
+
+		// width
+		if i < len(rdata) && rdata[i] == '*' {
+			arg, err := getArg(string(rdata[start : i+1]))
+			if err != nil {
+				return nil, err
 			}
-			newData = append(newData, []rune("{"+strconv.Itoa(index)+"}")...)
-			index++
+			spec = append(spec, '*')
+			specArgs = append(specArgs, arg)
+			i++
 		} else {
-			newData = append(newData, rdata[i])
+			end := digits(i)
+			spec = append(spec, rdata[i:end]...)
+			i = end
+		}
+
+		// precision
+		if i < len(rdata) && rdata[i] == '.' {
+			spec = append(spec, '.')
+			if i, err = argIndex(i + 1); err != nil {
+				return nil, err
+			}
+			if i < len(rdata) && rdata[i] == '*' {
+				arg, err := getArg(string(rdata[start : i+1]))
+				if err != nil {
+					return nil, err
+				}
+				spec = append(spec, '*')
+				specArgs = append(specArgs, arg)
+				i++
+			} else {
+				end := digits(i)
+				spec = append(spec, rdata[i:end]...)
+				i = end
+			}
+		}
+
+		if i, err = argIndex(i); err != nil {
+			return nil, err
+		}
+		if i >= len(rdata) {
+			return nil, fmt.Errorf("directive '%s' has no verb", string(rdata[start:]))
+		}
+
+		verb := rdata[i]
+		directive := string(rdata[start : i+1])
+		if verb == '%' {
+			// a literal percent; flags, width and precision are ignored, as in fmt.
+			newData = append(newData, '%')
+			continue
+		}
+		if !strings.ContainsRune(fmtVerbs, verb) {
+			return nil, fmt.Errorf("unsupported verb in directive '%s'", directive)
+		}
+		if verb == 'w' {
+			verb = 'v'
+		}
+		spec = append(spec, verb)
+
+		arg, err := getArg(directive)
+		if err != nil {
+			return nil, err
+		}
+
+		value := ph.value(string(spec), specArgs, arg)
+		suffix := ""
+		if ph.typedNumbers && string(spec) == "%d" {
+			suffix = ",number"
+		}
+
+		// only plain identifiers and constants are known to give the same value each time; f() is called again.
+		exprKey := types.ExprString(value)
+		for _, e := range append(specArgs, arg) {
+			if !isPlain(e) {
+				exprKey = fmt.Sprintf("%s %p", exprKey, e)
+			}
+		}
+		index, ok := ph.index[exprKey]
+		if !ok {
+			index = len(ph.index) + 1
+			ph.index[exprKey] = index
+			ph.mapData = append(ph.mapData,
+				&ast.KeyValueExpr{
+					Key: &ast.BasicLit{
+						Kind:  token.STRING,
+						Value: strconv.Quote(strconv.Itoa(index)),
+					},
+					Value: value,
+				})
+		}
+		newData = append(newData, []rune("{"+strconv.Itoa(index)+suffix+"}")...)
+	}
+	return newData, nil
+}
+
+// value returns the string expression replacing the directive spec applied to arg, whose width and precision
+// arguments are specArgs. %s, %d and %t are converted without fmt when the type of arg allows it; anything else
+// keeps its exact formatting through fmt.
+func (ph *fmtPlaceholders) value(spec string, specArgs []ast.Expr, arg ast.Expr) ast.Expr {
+	t := ph.argType(arg)
+	var basic *types.Basic
+	if t != nil {
+		basic, _ = t.Underlying().(*types.Basic)
+	}
+	is := func(kind types.BasicKind) bool {
+		return basic != nil && t == basic && (basic.Kind() == kind || basic.Kind() == untypedKinds[kind])
+	}
+	switch {
+	case spec == "%s" && is(types.String):
+		return arg
+	case spec == "%s" && t != nil && (types.Implements(t, errorType) || types.Implements(t, stringerType)):
+		return ph.call("fmt", "Sprint", arg)
+	case spec == "%s" && basic != nil && basic.Info()&types.IsString != 0:
+		return &ast.CallExpr{Fun: &ast.Ident{Name: "string"}, Args: []ast.Expr{arg}}
+	case spec == "%d" && is(types.Int):
+		return ph.call("strconv", "Itoa", arg)
+	case spec == "%d" && basic != nil && basic.Info()&types.IsInteger != 0:
+		if basic.Info()&types.IsUnsigned != 0 {
+			return ph.call("strconv", "FormatUint", convertTo("uint64", t, arg), &ast.BasicLit{Kind: token.INT, Value: "10"})
+		}
+		return ph.call("strconv", "FormatInt", convertTo("int64", t, arg), &ast.BasicLit{Kind: token.INT, Value: "10"})
+	case spec == "%t" && basic != nil && basic.Info()&types.IsBoolean != 0:
+		return ph.call("strconv", "FormatBool", convertTo("bool", t, arg))
+	case spec == "%v":
+		return ph.call("fmt", "Sprint", arg)
+	}
+	return ph.call("fmt", "Sprintf", append([]ast.Expr{&ast.BasicLit{
+		Kind:  token.STRING,
+		Value: strconv.Quote(spec),
+	}}, append(specArgs, arg)...)...)
+}
+
+// untypedKinds are the kinds of untyped constants, by the kind they default to.
+var untypedKinds = map[types.BasicKind]types.BasicKind{
+	types.String: types.UntypedString,
+	types.Int:    types.UntypedInt,
+	types.Bool:   types.UntypedBool,
+}
+
+var (
+	errorType    = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	stringerType = types.NewInterfaceType([]*types.Func{
+		types.NewFunc(token.NoPos, nil, "String", types.NewSignatureType(nil, nil, nil, nil,
+			types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.String])), false)),
+	}, nil).Complete()
+)
+
+// call returns the call to pkg.fun with args, recording the import of pkg.
+func (ph *fmtPlaceholders) call(pkg, fun string, args ...ast.Expr) *ast.CallExpr {
+	ph.needImport[pkg] = struct{}{}
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: pkg},
+			Sel: &ast.Ident{Name: fun},
+		},
+		Args: args,
+	}
+}
+
+// convertTo returns arg converted to the basic type name, unless it has that type already.
+func convertTo(name string, t types.Type, arg ast.Expr) ast.Expr {
+	if basic, ok := t.(*types.Basic); ok && (basic.Name() == name || basic.Info()&types.IsUntyped != 0) {
+		return arg
+	}
+	return &ast.CallExpr{Fun: &ast.Ident{Name: name}, Args: []ast.Expr{arg}}
+}
+
+// argType returns the type of arg, from the type information if loaded, or else from its literal or the declared
+// type of the identifier; nil if unknown.
+func (ph *fmtPlaceholders) argType(arg ast.Expr) types.Type {
+	if ph.info != nil {
+		if t := ph.info.TypeOf(arg); t != nil {
+			return t
 		}
 	}
-	return newData, mapData, needStrconv
+	switch a := arg.(type) {
+	case *ast.BasicLit:
+		switch a.Kind {
+		case token.STRING:
+			return types.Typ[types.UntypedString]
+		case token.INT:
+			return types.Typ[types.UntypedInt]
+		}
+	case *ast.Ident:
+		if a.Obj == nil {
+			return nil
+		}
+		var typ ast.Expr
+		switch d := a.Obj.Decl.(type) {
+		case *ast.Field:
+			typ = d.Type
+		case *ast.ValueSpec:
+			typ = d.Type
+		}
+		if id, ok := typ.(*ast.Ident); ok {
+			if tn, ok := types.Universe.Lookup(id.Name).(*types.TypeName); ok {
+				return tn.Type()
+			}
+		}
+	}
+	return nil
+}
+
+// isPlain reports whether e is an identifier or a literal, which evaluate to the same value each time.
+func isPlain(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	}
+	return false
 }
 
 func initHasLoad(ret *ast.FuncDecl, modName string) bool {
@@ -89,41 +359,46 @@ func initHasLoad(ret *ast.FuncDecl, modName string) bool {
 	return false
 }
 
-func sep(s string) string {
-	return string(filepath.Separator) + s + string(filepath.Separator)
-}
-
-func (l *Locer) injectTran(name string, ret *ast.CallExpr, f *ast.SelectorExpr, v *ast.BasicLit) (*ast.CallExpr, bool) {
+// injectTran stores the string v of call ret, and returns the equivalent goloc.Trnl call; if format is set, v is
+// converted from a format string with the args of ret, and a goloc.Trnlf call is returned.
+func (l *Locer) injectTran(name string, ret *ast.CallExpr, format bool, v *ast.BasicLit) (*ast.CallExpr, []string, error) {
 	stripped, err := strconv.Unquote(v.Value)
 	if err != nil {
-		Logger.Fatal().Err(err).Send()
-		return nil, false
+		return nil, nil, err
+	}
+	methToCall := "Trnl"
+	var mapData []ast.Expr
+	var imports []string
+	if format {
+		methToCall = "Trnlf"
+		ph := l.newFmtPlaceholders()
+		dataNew, err := ph.convert([]rune(stripped), ret)
+		if err != nil {
+			return nil, nil, err
+		}
+		stripped = string(dataNew)
+		mapData, imports = ph.mapData, ph.imports()
 	}
-	needStrConvImport := false
 
-	itemName, isDup := noDupStrings[stripped]
+	// dedup on the stored text, so strings already extracted into Trnlf calls are matched too.
+	dedup := stripped
+	cat := l.catalog()
+	itemName, isDup := l.noDupStrings[dedup]
+	id := cat.Count(name)
 	if !isDup {
-		dataCount[name]++
-		itemName = name + ":" + strconv.Itoa(dataCount[name])
-		noDupStrings[stripped] = itemName
-		newDataNames[name] = append(newDataNames[name], itemName)
+		id = cat.nextID(name)
+		itemName = l.newKey(name, id, dedup)
+		l.noDupStrings[dedup] = itemName
+		l.newDataNames[name] = append(l.newDataNames[name], itemName)
 	}
 
 	args := []ast.Expr{
-		&ast.Ident{Name: "lang"},
 		&ast.BasicLit{
 			Kind:  token.STRING,
 			Value: strconv.Quote(itemName),
 		},
 	}
-
-	methToCall := "Trnl"
-	if _, ok := l.Funcs[f.Sel.Name]; ok || f.Sel.Name == "Addf" {
-		methToCall = "Trnlf"
-		dataNew, mapData, needStrconv := parseFmtString([]rune(stripped), ret)
-		needStrConvImport = needStrconv
-
-		stripped = string(dataNew)
+	if methToCall == "Trnlf" {
 		args = append(args, &ast.CompositeLit{
 			Type: &ast.MapType{
 				Key: &ast.BasicLit{
@@ -140,30 +415,151 @@ func (l *Locer) injectTran(name string, ret *ast.CallExpr, f *ast.SelectorExpr,
 	}
 
 	if !isDup {
-		for lang := range newData {
-			newData[lang][name][itemName] = Value{
-				Id:      dataCount[name],
+		for lang := range l.newData {
+			l.newData[lang][name][itemName] = Value{
+				Id:      id,
 				Name:    itemName,
 				Value:   "",
 				Comment: stripped,
 			}
 		}
 		// set data only for default value
-		newData[l.DefaultLang][name][itemName] = Value{
-			Id:      dataCount[name],
+		l.newData[l.DefaultLang][name][itemName] = Value{
+			Id:      id,
 			Name:    itemName,
 			Value:   stripped,
 			Comment: itemName,
 		}
 	}
 
+	return l.tranCall(methToCall, args...), imports, nil
+}
+
+// injectPlural stores the one/other forms of a plural call, and returns the equivalent goloc.Trnpf call.
+// The format arguments of both strings start at the count, which is the third argument of ret.
+func (l *Locer) injectPlural(name string, ret *ast.CallExpr, one *ast.BasicLit, other *ast.BasicLit) (*ast.CallExpr, []string, error) {
+	strippedOne, err := strconv.Unquote(one.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+	strippedOther, err := strconv.Unquote(other.Value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmtCall := &ast.CallExpr{Args: ret.Args[1:], Ellipsis: ret.Ellipsis}
+	ph := l.newFmtPlaceholders()
+	oneData, err := ph.convert([]rune(strippedOne), fmtCall)
+	if err != nil {
+		return nil, nil, fmt.Errorf("one form: %w", err)
+	}
+	otherData, err := ph.convert([]rune(strippedOther), fmtCall)
+	if err != nil {
+		return nil, nil, fmt.Errorf("other form: %w", err)
+	}
+
+	defVal := Value{}
+	for _, f := range pluralForms(l.DefaultLang) {
+		p := Plural{Form: f, Value: string(otherData)}
+		if f == "one" {
+			p.Value = string(oneData)
+		}
+		defVal.Plurals = append(defVal.Plurals, p)
+	}
+
+	cat := l.catalog()
+	itemName, isDup := l.noDupStrings[dedupKey(defVal)]
+	if !isDup {
+		id := cat.nextID(name)
+		itemName = l.newKey(name, id, dedupKey(defVal))
+		l.noDupStrings[dedupKey(defVal)] = itemName
+		l.newDataNames[name] = append(l.newDataNames[name], itemName)
+
+		for lang := range l.newData {
+			l.newData[lang][name][itemName] = Value{
+				Id:      id,
+				Name:    itemName,
+				Plurals: emptyPlurals(lang),
+				Comment: string(otherData),
+			}
+		}
+		// set data only for default value
+		defVal.Id = id
+		defVal.Name = itemName
+		defVal.Comment = itemName
+		l.newData[l.DefaultLang][name][itemName] = defVal
+	}
+
+	return l.tranCall("Trnpf",
+		&ast.BasicLit{
+			Kind:  token.STRING,
+			Value: strconv.Quote(itemName),
+		},
+		ret.Args[2],
+		&ast.CompositeLit{
+			Type: &ast.MapType{
+				Key:   &ast.Ident{Name: "string"},
+				Value: &ast.Ident{Name: "string"},
+			},
+			Elts: ph.mapData,
+		},
+	), ph.imports(), nil
+}
+
+// ctxFuncs are the goloc functions translating in the language of a context, by the function they replace.
+var ctxFuncs = map[string]string{"Trnl": "T", "Trnlf": "Tf", "Trnp": "Tp", "Trnpf": "Tpf"}
+
+// tranCall returns the call to the goloc function meth, such as Trnlf, with lang and args. When converting a
+// function with a context parameter, the context and the matching ctxFuncs function are used instead.
+func (l *Locer) tranCall(meth string, args ...ast.Expr) *ast.CallExpr {
+	var langArg ast.Expr = &ast.Ident{Name: "lang"}
+	if l.ctxParam != "" {
+		meth, langArg = ctxFuncs[meth], &ast.Ident{Name: l.ctxParam}
+	}
 	return &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
 			X:   &ast.Ident{Name: "goloc"},
-			Sel: &ast.Ident{Name: methToCall},
+			Sel: &ast.Ident{Name: meth},
 		},
-		Args: args,
-	}, needStrConvImport
+		Args: append([]ast.Expr{langArg}, args...),
+	}
+}
+
+// untranslated returns the empty version of def to be filled in by the translators of lang.
+func untranslated(lang string, def Value) Value {
+	out := Value{
+		Id:      def.Id,
+		Name:    def.Name,
+		Value:   "",
+		Comment: def.Value,
+	}
+	if len(def.Plurals) > 0 {
+		out.Plurals = emptyPlurals(lang)
+		out.Comment = def.Plural("other")
+	}
+	if def.Select != nil {
+		out.Select = &Select{Arg: def.Select.Arg}
+		for _, c := range def.Select.Cases {
+			out.Select.Cases = append(out.Select.Cases, Case{Key: c.Key})
+		}
+		out.Comment = def.Select.String()
+	}
+	return out
+}
+
+// dedupKey returns the text used to detect duplicate strings; plurals and selects are keyed by all their variants.
+func dedupKey(v Value) string {
+	if v.Select != nil {
+		return "select:" + v.Select.Arg + "=" + v.Select.String()
+	}
+	if len(v.Plurals) == 0 {
+		return v.Value
+	}
+	var sb strings.Builder
+	for _, p := range v.Plurals {
+		sb.WriteString(p.Form + "=" + p.Value + "\x00")
+	}
+	return sb.String()
 }
 
 func stringSlicesEqual(a, b []string) bool {
@@ -180,7 +576,7 @@ func stringSlicesEqual(a, b []string) bool {
 func (l *Locer) saveMap(newData map[string]map[string]map[string]Value, newDataNames map[string][]string) error {
 	for lang, filenameMap := range newData {
 		for modName, modData := range filenameMap {
-			names := loadOriginalModuleOrder(modName)
+			names := l.loadOriginalModuleOrder(modName)
 			newNames := newDataNames[modName]
 			if len(names) < len(newNames) || !stringSlicesEqual(names[len(names)-len(newNames):], newNames) {
 				names = append(names, newNames...)
@@ -203,35 +599,14 @@ func (l *Locer) saveMap(newData map[string]map[string]map[string]Value, newDataN
 
 				xmlOutput.Rows = append(xmlOutput.Rows, langData)
 			}
-			xmlOutput.Counter = dataCount[modName]
-
-			err := func() error {
-				// TODO: other filetypes than xml
-				w := os.Stdout
-				if l.Apply {
-					// TODO: choose translationDir
-					xmlName := strings.TrimSuffix(path.Join(translationDir, lang, modName), path.Ext(modName)) + ".xml"
-					err := os.MkdirAll(filepath.Dir(xmlName), 0755)
-					if err != nil {
-						return err
-					}
-					f, err := os.Create(xmlName)
-					if err != nil {
-						return err
-					}
-					defer ioClose(f)
-					// set encoding output
-					w = f
-				}
-				_, _ = w.WriteString(xml.Header)
-				enc := xml.NewEncoder(w)
-				enc.Indent("", "    ")
-				if err := enc.Encode(xmlOutput); err != nil {
-					return err
-				}
-				_, _ = w.WriteString("\n")
-				return nil
-			}()
+			xmlOutput.Counter = l.catalog().Count(modName)
+
+			var err error
+			if format := l.fileFormat(); l.Apply {
+				err = writeModuleFile(format, filepath.Join(l.transDir(), moduleFile(format, lang, modName)), xmlOutput)
+			} else {
+				err = format.Encode(os.Stdout, xmlOutput)
+			}
 			if err != nil {
 				return err
 			}
@@ -240,22 +615,15 @@ func (l *Locer) saveMap(newData map[string]map[string]map[string]Value, newDataN
 	return nil
 }
 
-func loadOriginalModuleOrder(modName string) (out []string) {
-	f, err := os.Open(path.Join(translationDir, DefaultLang, strings.TrimSuffix(modName, path.Ext(modName))+".xml"))
+func (l *Locer) loadOriginalModuleOrder(modName string) (out []string) {
+	xmlData, err := readModule(os.DirFS(l.transDir()), l.fileFormat(), l.DefaultLang, modName)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return
 		}
 		Logger.Fatal().Err(err).Send()
 		return
 	}
-	defer ioClose(f)
-	dec := xml.NewDecoder(f)
-	var xmlData Translation
-	err = dec.Decode(&xmlData)
-	if err != nil {
-		Logger.Fatal().Err(err).Send()
-	}
 	for _, row := range xmlData.Rows {
 		out = append(out, row.Name)
 	}
@@ -0,0 +1,190 @@
+package loc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+func TestParseFmtString(t *testing.T) {
+	tests := []struct {
+		call    string
+		want    string
+		wantMap []string
+		imports []string
+		wantErr string
+	}{
+		{call: `f("hi %s", name)`, want: "hi {1}", wantMap: []string{`"1": name`}},
+		{call: `f("%d of %t", n, ok)`, want: "{1} of {2}", wantMap: []string{`"1": strconv.Itoa(n)`, `"2": strconv.FormatBool(ok)`}, imports: []string{"strconv"}},
+		{call: `f("%d %d %s", i64, u, err)`, want: "{1} {2} {3}", wantMap: []string{`"1": strconv.FormatInt(i64, 10)`, `"2": strconv.FormatUint(uint64(u), 10)`, `"3": fmt.Sprint(err)`}, imports: []string{"fmt", "strconv"}},
+		{call: `f("%s %d", y, z)`, want: "{1} {2}", wantMap: []string{`"1": fmt.Sprintf("%s", y)`, `"2": fmt.Sprintf("%d", z)`}, imports: []string{"fmt"}},
+		{call: `f("100%% %v", x)`, want: "100% {1}", wantMap: []string{`"1": fmt.Sprint(x)`}, imports: []string{"fmt"}},
+		{call: `f("%q %x %.2f", s, b, f)`, want: "{1} {2} {3}", wantMap: []string{`"1": fmt.Sprintf("%q", s)`, `"2": fmt.Sprintf("%x", b)`, `"3": fmt.Sprintf("%.2f", f)`}, imports: []string{"fmt"}},
+		{call: `f("%-8s|%+05d", s, n)`, want: "{1}|{2}", wantMap: []string{`"1": fmt.Sprintf("%-8s", s)`, `"2": fmt.Sprintf("%+05d", n)`}},
+		{call: `f("%*d %.*f", w, n, p, x)`, want: "{1} {2}", wantMap: []string{`"1": fmt.Sprintf("%*d", w, n)`, `"2": fmt.Sprintf("%.*f", p, x)`}},
+		{call: `f("%[2]s then %[1]s and %[2]s", a, b)`, want: "{1} then {2} and {1}", wantMap: []string{`"1": b`, `"2": a`}},
+		{call: `f("%[1]d %[1]x", n)`, want: "{1} {2}", wantMap: []string{`"1": strconv.Itoa(n)`, `"2": fmt.Sprintf("%x", n)`}},
+		{call: `f("%s and %s", g(), g())`, want: "{1} and {2}", wantMap: []string{`"1": fmt.Sprintf("%s", g())`, `"2": fmt.Sprintf("%s", g())`}},
+		{call: `f("%s and %[1]s", g())`, want: "{1} and {1}", wantMap: []string{`"1": fmt.Sprintf("%s", g())`}},
+		{call: `f("%#v %T %p %w", a, b, c, d)`, want: "{1} {2} {3} {4}"},
+		{call: `f("%s %s", a)`, wantErr: "needs argument 2, but only 1 given"},
+		{call: `f("%y", a)`, wantErr: "unsupported verb"},
+		{call: `f("50%", a)`, wantErr: "has no verb"},
+		{call: `f("%[0]d", a)`, wantErr: "invalid argument index"},
+		{call: `f("%s", a...)`, wantErr: "variadic"},
+	}
+	for _, tc := range tests {
+		// the declared types of the arguments are used without type information.
+		src := "package p\nfunc _(name, a, b string, n int, ok bool, i64 int64, u uint8, err error, x any) {\n" + tc.call + "\n}"
+		file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := file.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr)
+		format := call.Args[0].(*ast.BasicLit).Value
+		format = format[1 : len(format)-1]
+
+		got, mapData, imports, err := parseFmtString([]rune(format), call)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tc.call, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error = %v", tc.call, err)
+			continue
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.call, string(got), tc.want)
+		}
+		if tc.wantMap != nil {
+			var gotMap []string
+			for _, kv := range mapData {
+				kv := kv.(*ast.KeyValueExpr)
+				gotMap = append(gotMap, types.ExprString(kv.Key)+": "+types.ExprString(kv.Value))
+			}
+			if !slices.Equal(gotMap, tc.wantMap) {
+				t.Errorf("%s: map = %v, want %v", tc.call, gotMap, tc.wantMap)
+			}
+		}
+		if tc.imports != nil && !slices.Equal(imports, tc.imports) {
+			t.Errorf("%s: imports = %v, want %v", tc.call, imports, tc.imports)
+		}
+	}
+}
+
+// TestFmtArgsTypeCheck converts format calls with arguments of various types, with and without type information,
+// and type-checks the resulting maps.
+func TestFmtArgsTypeCheck(t *testing.T) {
+	src := `package p
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type count int64
+
+type name string
+
+type flag bool
+
+type temp float64
+
+func (t temp) String() string { return fmt.Sprintf("%.1f°", float64(t)) }
+
+func Sendf(format string, args ...interface{}) {}
+
+func next() int64 { return 1 }
+
+func send(err error, n int64, u uint, c count, nm name, fl flag, s fmt.Stringer, tp temp, d time.Duration, b []byte) {
+	Sendf("failed: %s", err)
+	Sendf("%d of %d, %d", n, u, c)
+	Sendf("%s %s %s %s %s", nm, s, tp, d, b)
+	Sendf("%t %t %v", fl, true, tp)
+	Sendf("%d then %d", next(), next())
+	Sendf("%s %d %s", errors.New("x"), 3, "lit")
+}
+`
+	for _, typed := range []bool{false, true} {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "p.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := &Locer{}
+		if typed {
+			l.info = &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+			conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+			if _, err := conf.Check("p", fset, []*ast.File{file}, l.info); err != nil {
+				t.Fatal(err)
+			}
+		}
+		imports := map[string]struct{}{}
+		astutil.Apply(file, func(c *astutil.Cursor) bool {
+			stmt, ok := c.Node().(*ast.ExprStmt)
+			if !ok {
+				return true
+			}
+			call := stmt.X.(*ast.CallExpr)
+			lit := call.Args[0].(*ast.BasicLit)
+			format := lit.Value[1 : len(lit.Value)-1]
+			ph := l.newFmtPlaceholders()
+			if _, err := ph.convert([]rune(format), call); err != nil {
+				t.Fatal(err)
+			}
+			for _, imp := range ph.imports() {
+				imports[imp] = struct{}{}
+			}
+			c.Replace(&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.Ident{Name: "_"}},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.CompositeLit{
+					Type: &ast.MapType{Key: &ast.Ident{Name: "string"}, Value: &ast.Ident{Name: "string"}},
+					Elts: ph.mapData,
+				}},
+			})
+			return false
+		}, nil)
+		for imp := range imports {
+			astutil.AddImport(fset, file, imp)
+		}
+
+		var buf bytes.Buffer
+		if err := format.Node(&buf, fset, file); err != nil {
+			t.Fatal(err)
+		}
+		outFset := token.NewFileSet()
+		out, err := parser.ParseFile(outFset, "p.go", buf.Bytes(), 0)
+		if err != nil {
+			t.Fatalf("typed=%v: %v\n%s", typed, err, buf.Bytes())
+		}
+		conf := types.Config{Importer: importer.ForCompiler(outFset, "source", nil)}
+		if _, err := conf.Check("p", outFset, []*ast.File{out}, nil); err != nil {
+			t.Errorf("typed=%v: converted calls don't type-check: %v\n%s", typed, err, buf.Bytes())
+		}
+		if typed {
+			for _, want := range []string{`"1": strconv.FormatInt(n, 10)`, `"2": strconv.FormatUint(uint64(u), 10)`,
+				`"3": strconv.FormatInt(int64(c), 10)`, `"1": string(nm)`, `"2": fmt.Sprint(s)`, `"3": fmt.Sprint(tp)`,
+				`"5": fmt.Sprintf("%s", b)`, `"1": strconv.FormatBool(bool(fl))`} {
+				if !bytes.Contains(buf.Bytes(), []byte(want)) {
+					t.Errorf("typed conversion %s missing:\n%s", want, buf.Bytes())
+				}
+			}
+		}
+		if !bytes.Contains(buf.Bytes(), []byte(`"1": fmt.Sprint(err)`)) {
+			t.Errorf("typed=%v: error not converted with fmt.Sprint:\n%s", typed, buf.Bytes())
+		}
+	}
+}
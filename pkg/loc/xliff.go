@@ -0,0 +1,357 @@
+package loc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	xliff12NS = "urn:oasis:names:tc:xliff:document:1.2"
+	xliff2NS  = "urn:oasis:names:tc:xliff:document:2.0"
+)
+
+type xliff12 struct {
+	XMLName xml.Name      `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string        `xml:"version,attr"`
+	Files   []xliff12File `xml:"file"`
+}
+
+type xliff12File struct {
+	Original   string        `xml:"original,attr"`
+	SourceLang string        `xml:"source-language,attr"`
+	TargetLang string        `xml:"target-language,attr"`
+	Datatype   string        `xml:"datatype,attr"`
+	Units      []xliff12Unit `xml:"body>trans-unit"`
+}
+
+type xliff12Unit struct {
+	ID     string         `xml:"id,attr"`
+	Source xliffInline    `xml:"source"`
+	Target *xliff12Target `xml:"target"`
+	Notes  []xliffNote    `xml:"note"`
+}
+
+type xliff12Target struct {
+	State string `xml:"state,attr,omitempty"`
+	Inner string `xml:",innerxml"`
+}
+
+type xliffNote struct {
+	From     string `xml:"from,attr,omitempty"`     // 1.2
+	Category string `xml:"category,attr,omitempty"` // 2.0
+	Text     string `xml:",chardata"`
+}
+
+type xliff2 struct {
+	XMLName xml.Name     `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string       `xml:"version,attr"`
+	SrcLang string       `xml:"srcLang,attr"`
+	TrgLang string       `xml:"trgLang,attr"`
+	Files   []xliff2File `xml:"file"`
+}
+
+type xliff2File struct {
+	ID       string       `xml:"id,attr"`
+	Original string       `xml:"original,attr"`
+	Units    []xliff2Unit `xml:"unit"`
+}
+
+// xliff2Unit keeps the goloc key in name, as unit ids must be NMTOKENs.
+type xliff2Unit struct {
+	ID      string        `xml:"id,attr"`
+	Name    string        `xml:"name,attr"`
+	Notes   *xliff2Notes  `xml:"notes"`
+	Segment xliff2Segment `xml:"segment"`
+}
+
+type xliff2Notes struct {
+	Notes []xliffNote `xml:"note"`
+}
+
+type xliff2Segment struct {
+	State  string       `xml:"state,attr,omitempty"`
+	Source xliffInline  `xml:"source"`
+	Target *xliffInline `xml:"target"`
+}
+
+// xliffInline is source or target content, with placeholders as <ph> elements.
+type xliffInline struct {
+	Inner string `xml:",innerxml"`
+}
+
+// xliff12States maps goloc states to XLIFF 1.2 states, which have no "reviewed".
+var xliff12States = map[string]string{
+	StateNew:        "new",
+	StateTranslated: "translated",
+	StateReviewed:   "signed-off",
+}
+
+// xliff2States maps goloc states to XLIFF 2.0 states.
+var xliff2States = map[string]string{
+	StateNew:        "initial",
+	StateTranslated: "translated",
+	StateReviewed:   "reviewed",
+}
+
+// xliffState returns the goloc state of an XLIFF 1.2 or 2.0 state.
+func xliffState(state string) string {
+	switch state {
+	case "signed-off", "final", "reviewed":
+		return StateReviewed
+	case "", "new", "initial", "needs-translation":
+		return StateNew
+	default:
+		return StateTranslated
+	}
+}
+
+// encodeInline escapes text as XLIFF inline content, protecting placeholders such as {1} as <ph> elements. XLIFF 1.2
+// keeps the placeholder as the element content; XLIFF 2.0 uses an empty element with the placeholder as disp and
+// equiv.
+func encodeInline(text string, v2 bool) string {
+	var sb strings.Builder
+	last := 0
+	for i, m := range curliesRex.FindAllStringIndex(text, -1) {
+		_ = xml.EscapeText(&sb, []byte(text[last:m[0]]))
+		var ph strings.Builder
+		_ = xml.EscapeText(&ph, []byte(text[m[0]:m[1]]))
+		id := strconv.Itoa(i + 1)
+		if v2 {
+			fmt.Fprintf(&sb, `<ph id="ph%s" disp="%s" equiv="%s"/>`, id, ph.String(), ph.String())
+		} else {
+			fmt.Fprintf(&sb, `<ph id="%s">%s</ph>`, id, ph.String())
+		}
+		last = m[1]
+	}
+	_ = xml.EscapeText(&sb, []byte(text[last:]))
+	return sb.String()
+}
+
+// decodeInline returns the plain text of XLIFF inline content; <ph> elements are replaced by the placeholder they
+// protect, and any other markup is dropped.
+func decodeInline(inner string) (string, error) {
+	dec := xml.NewDecoder(strings.NewReader("<x>" + inner + "</x>"))
+	var sb strings.Builder
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.StartElement:
+			if t.Name.Local != "ph" {
+				continue
+			}
+			for _, a := range t.Attr {
+				if a.Name.Local == "equiv" {
+					sb.WriteString(a.Value)
+					break
+				}
+			}
+		}
+	}
+}
+
+// xliffNotes returns the notes of a value: the developer comment of def, and any comment translators left on cur.
+func xliffNotes(lang string, def Value, cur Value, v2 bool) []xliffNote {
+	var notes []xliffNote
+	add := func(who string, text string) {
+		n := xliffNote{From: who, Text: text}
+		if v2 {
+			n = xliffNote{Category: who, Text: text}
+		}
+		notes = append(notes, n)
+	}
+	if def.Comment != "" && def.Comment != def.Name {
+		add("developer", def.Comment)
+	}
+	if c := translatorComment(lang, def, cur); c != "" {
+		add("translator", c)
+	}
+	return notes
+}
+
+func (l *Locer) exportXLIFF(sets []moduleSet, langs []string, outDir string, v2 bool) error {
+	if len(langs) == 0 {
+		return fmt.Errorf("no languages to export besides %s; create one first", l.DefaultLang)
+	}
+	for _, lang := range langs {
+		var doc any
+		if v2 {
+			doc = l.buildXLIFF2(sets, lang)
+		} else {
+			doc = l.buildXLIFF12(sets, lang)
+		}
+		if err := writeXLIFFFile(filepath.Join(outDir, lang+".xlf"), doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Locer) buildXLIFF12(sets []moduleSet, lang string) *xliff12 {
+	doc := &xliff12{Version: "1.2"}
+	for _, set := range sets {
+		f := xliff12File{Original: set.name, SourceLang: l.DefaultLang, TargetLang: lang, Datatype: "plaintext"}
+		rows := set.rows(lang)
+		for i, def := range set.def.Rows {
+			if def.Name == "" {
+				continue
+			}
+			notes := xliffNotes(lang, def, rows[i], false)
+			for _, u := range units(def, rows[i], lang) {
+				f.Units = append(f.Units, xliff12Unit{
+					ID:     u.id,
+					Source: xliffInline{Inner: encodeInline(u.source, false)},
+					Target: &xliff12Target{
+						State: xliff12States[unitState(rows[i], u.target)],
+						Inner: encodeInline(u.target, false),
+					},
+					Notes: notes,
+				})
+			}
+		}
+		doc.Files = append(doc.Files, f)
+	}
+	return doc
+}
+
+func (l *Locer) buildXLIFF2(sets []moduleSet, lang string) *xliff2 {
+	doc := &xliff2{Version: "2.0", SrcLang: l.DefaultLang, TrgLang: lang}
+	for n, set := range sets {
+		f := xliff2File{ID: "f" + strconv.Itoa(n+1), Original: set.name}
+		rows := set.rows(lang)
+		for i, def := range set.def.Rows {
+			if def.Name == "" {
+				continue
+			}
+			var notes *xliff2Notes
+			if n := xliffNotes(lang, def, rows[i], true); len(n) > 0 {
+				notes = &xliff2Notes{Notes: n}
+			}
+			for _, u := range units(def, rows[i], lang) {
+				f.Units = append(f.Units, xliff2Unit{
+					ID:    "u" + strconv.Itoa(len(f.Units)+1),
+					Name:  u.id,
+					Notes: notes,
+					Segment: xliff2Segment{
+						State:  xliff2States[unitState(rows[i], u.target)],
+						Source: xliffInline{Inner: encodeInline(u.source, true)},
+						Target: &xliffInline{Inner: encodeInline(u.target, true)},
+					},
+				})
+			}
+		}
+		doc.Files = append(doc.Files, f)
+	}
+	return doc
+}
+
+func writeXLIFFFile(filename string, doc any) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer ioClose(f)
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, "\n")
+	return err
+}
+
+// importXLIFF merges the targets of an XLIFF 1.2 or 2.0 file into its target language.
+func (l *Locer) importXLIFF(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+
+	var lang string
+	imported := make(map[string]importedUnit)
+	add := func(id string, target string, state string, notes []xliffNote) error {
+		text, err := decodeInline(target)
+		if err != nil {
+			return fmt.Errorf("unit %s: %w", id, err)
+		}
+		u := importedUnit{text: text, state: xliffState(state)}
+		for _, n := range notes {
+			if n.From == "translator" || n.Category == "translator" {
+				u.comment = n.Text
+			}
+		}
+		imported[id] = u
+		return nil
+	}
+
+	switch root.XMLName.Space {
+	case xliff12NS:
+		var doc xliff12
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		for _, f := range doc.Files {
+			lang = f.TargetLang
+			for _, u := range f.Units {
+				if u.Target == nil {
+					continue
+				}
+				if err := add(u.ID, u.Target.Inner, u.Target.State, u.Notes); err != nil {
+					return err
+				}
+			}
+		}
+	case xliff2NS:
+		var doc xliff2
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		lang = doc.TrgLang
+		for _, f := range doc.Files {
+			for _, u := range f.Units {
+				if u.Segment.Target == nil {
+					continue
+				}
+				var notes []xliffNote
+				if u.Notes != nil {
+					notes = u.Notes.Notes
+				}
+				if err := add(u.Name, u.Segment.Target.Inner, u.Segment.State, notes); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported XLIFF namespace '%s'", root.XMLName.Space)
+	}
+
+	if lang == "" {
+		return errors.New("no target language set")
+	}
+	return l.mergeUnits(file, lang, imported)
+}
@@ -0,0 +1,89 @@
+package loc
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		text string
+		v12  string
+		v2   string
+	}{
+		{"plain <b>", "plain &lt;b&gt;", "plain &lt;b&gt;"},
+		{"hi {1}, {2,number}", `hi <ph id="1">{1}</ph>, <ph id="2">{2,number}</ph>`,
+			`hi <ph id="ph1" disp="{1}" equiv="{1}"/>, <ph id="ph2" disp="{2,number}" equiv="{2,number}"/>`},
+	}
+	for _, tt := range tests {
+		for v2, want := range map[bool]string{false: tt.v12, true: tt.v2} {
+			got := encodeInline(tt.text, v2)
+			if got != want {
+				t.Errorf("encodeInline(%q, %v) = %q, want %q", tt.text, v2, got, want)
+			}
+			back, err := decodeInline(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if back != tt.text {
+				t.Errorf("decodeInline(%q) = %q, want %q", got, back, tt.text)
+			}
+		}
+	}
+}
+
+func TestXLIFFRoundTrip(t *testing.T) {
+	for _, format := range []string{"xliff", "xliff2"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestTree(t, dir, testTree())
+			l := &Locer{DefaultLang: "en-GB", TransDir: dir, Apply: true}
+			out := filepath.Join(dir, "export")
+			if err := l.Export(format, out); err != nil {
+				t.Fatal(err)
+			}
+			file := filepath.Join(out, "de-DE.xlf")
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := string(data)
+			for _, want := range []string{"bot/main.go:1", "bot/main.go:2|other", "bot/main.go:3|male", `<ph id=`} {
+				if !strings.Contains(doc, want) {
+					t.Errorf("%s export is missing %q:\n%s", format, want, doc)
+				}
+			}
+
+			// a translator reviews the first string and translates the male case
+			doc = strings.Replace(doc, `"translated"`, map[string]string{"xliff": `"signed-off"`, "xliff2": `"reviewed"`}[format], 1)
+			doc = strings.Replace(doc, "hallo", "servus", 1)
+			doc = regexp.MustCompile(`(>he</source>\s*<target[^>]*>)</target>`).ReplaceAllString(doc, "${1}er</target>")
+			if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := l.Import("", []string{file}); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := readModule(os.DirFS(dir), XMLFormat, "de-DE", "bot/main.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Rows) != 3 {
+				t.Fatalf("got %d rows, want 3", len(got.Rows))
+			}
+			if got.Rows[0].Value != "servus {1}" || got.Rows[0].State != StateReviewed {
+				t.Errorf("row 1 = %q (%s), want %q (%s)", got.Rows[0].Value, got.Rows[0].State, "servus {1}", StateReviewed)
+			}
+			if c := got.Rows[2].Select.Case("male"); c != "er" {
+				t.Errorf("select male = %q, want %q", c, "er")
+			}
+			if got.Rows[2].State != "" {
+				t.Errorf("row 3 state = %q, want it unset", got.Rows[2].State)
+			}
+		})
+	}
+}